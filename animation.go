@@ -0,0 +1,275 @@
+package streamdeck
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"time"
+)
+
+// Blink flashes a button with the given color the given number of times,
+// alternating with the image that was previously set on the button (or black
+// if there wasn't one). The previous image gets restored when done. Setting
+// another image on the button stops the blinking.
+func (d *Device) Blink(index uint8, c color.Color, times int, interval time.Duration) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+	if times < 0 {
+		return errors.New("blink count must not be negative")
+	}
+	if interval <= 0 {
+		return errors.New("blink interval must be positive")
+	}
+	if times == 0 {
+		return nil
+	}
+
+	prev, ok := d.images.Get(index)
+	if !ok {
+		prev = newKeyImage(d.solidImage(color.RGBA{0, 0, 0, 255}))
+	}
+	on := newKeyImage(d.solidImage(c))
+
+	done := d.startAnimation(index)
+	defer d.finishAnimation(index, done)
+
+	frames := make([]keyImage, 0, times*2)
+	for i := 0; i < times; i++ {
+		frames = append(frames, on, prev)
+	}
+
+	for i, frame := range frames {
+		if ok, err := d.setAnimationFrame(index, frame, done, false); !ok || err != nil {
+			return err
+		}
+		if i == len(frames)-1 {
+			break
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-done:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// startAnimation stops any animation running on a button and returns a channel
+// which gets closed when the new animation should stop.
+func (d *Device) startAnimation(index uint8) chan struct{} {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	d.stopAnimation(index)
+	done := make(chan struct{})
+	d.animations[index] = done
+	return done
+}
+
+// finishAnimation unregisters an animation once it has ended.
+func (d *Device) finishAnimation(index uint8, done chan struct{}) {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	if d.animations[index] == done {
+		delete(d.animations, index)
+	}
+}
+
+// stopAnimation stops the animation running on a button, if any. It returns
+// true if there was one, in which case the button may show a frame rather
+// than its cached image. The caller must hold the writeMutex.
+func (d *Device) stopAnimation(index uint8) bool {
+	done, ok := d.animations[index]
+	if ok {
+		close(done)
+		delete(d.animations, index)
+	}
+	return ok
+}
+
+// setAnimationFrame shows a frame on a button unless the animation has been
+// stopped. It returns false if the animation has been stopped. Frames don't
+// replace the button's cached image, so effects can restore it afterwards,
+// unless keep is true, e.g. for the frame an animation ends with.
+func (d *Device) setAnimationFrame(index uint8, frame keyImage, done chan struct{}, keep bool) (bool, error) {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	select {
+	case <-done:
+		return false, nil
+	default:
+	}
+
+	if keep {
+		return true, d.setImage(index, frame.img, frame.opts)
+	}
+	return true, d.showImage(index, frame.img, frame.opts)
+}
+
+// SetImageTTL sets an image on a button and restores the image that was
+// previously set on it (or black if there wasn't one) after the given
+// duration. Setting another image on the button before then cancels the
+// restore.
+func (d *Device) SetImageTTL(index uint8, img image.Image, ttl time.Duration) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if err := d.validateKeyImage(index, img); err != nil {
+		return err
+	}
+
+	prev, ok := d.images.Get(index)
+	if !ok {
+		prev = newKeyImage(d.solidImage(color.RGBA{0, 0, 0, 255}))
+	}
+
+	done := d.startAnimation(index)
+	if _, err := d.setAnimationFrame(index, newKeyImage(img), done, false); err != nil {
+		d.finishAnimation(index, done)
+		return err
+	}
+
+	go func() {
+		defer d.finishAnimation(index, done)
+
+		select {
+		case <-time.After(ttl):
+			_, _ = d.setAnimationFrame(index, prev, done, false)
+		case <-done:
+		}
+	}()
+
+	return nil
+}
+
+// AnimationOptions customizes how SetAnimation plays its frames.
+type AnimationOptions struct {
+	// Loops is how many times the animation gets played. Zero plays it until
+	// another image is set on the button, negative counts are rejected.
+	Loops int
+	// SkipFrames drops frames when the device can't keep up with the frame
+	// rate, keeping the animation's wall-clock timing. Otherwise every frame
+	// gets shown and the animation slows down to what the device can
+	// sustain.
+	SkipFrames bool
+	// OnFrameRate gets called about once a second with the frame rate the
+	// animation actually achieved.
+	OnFrameRate func(fps float64)
+}
+
+// SetAnimation plays the given frames on a button, showing each of them for
+// the given delay, in the background. If writing a frame takes longer than
+// the delay, e.g. on a slow USB hub, the animation adapts to the rate the
+// device can sustain instead of falling behind. Setting another image on the
+// button stops the animation; when it ends by itself, its last frame stays.
+// A single frame simply gets set on the button.
+func (d *Device) SetAnimation(index uint8, frames []image.Image, delay time.Duration, opts AnimationOptions) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if len(frames) == 0 {
+		return errors.New("animation has no frames")
+	}
+	if delay <= 0 {
+		return errors.New("animation frame delay must be positive")
+	}
+	if opts.Loops < 0 {
+		return errors.New("animation loop count must not be negative")
+	}
+	for _, img := range frames {
+		if err := d.validateKeyImage(index, img); err != nil {
+			return err
+		}
+	}
+
+	_, err := d.startFrames(index, len(frames), func(i int) image.Image {
+		return frames[i]
+	}, delay, opts)
+	return err
+}
+
+// startFrames shows the first of count frames on a button and plays the
+// others in the background, like SetAnimation does. The first frame stays
+// cached if it's the only one, or the last one of a finite animation. It
+// returns the channel which stops the animation, or nil if there are no more
+// frames to play.
+func (d *Device) startFrames(index uint8, count int, frame func(i int) image.Image, delay time.Duration, opts AnimationOptions) (chan struct{}, error) {
+	static := count == 1
+
+	done := d.startAnimation(index)
+	if _, err := d.setAnimationFrame(index, newKeyImage(frame(0)), done, static); err != nil || static {
+		d.finishAnimation(index, done)
+		return nil, err
+	}
+
+	go func() {
+		defer d.finishAnimation(index, done)
+		d.runAnimation(index, count, frame, delay, opts, done)
+	}()
+	return done, nil
+}
+
+// runAnimation plays an animation of count frames until it's done or gets
+// stopped. The first frame has already been shown.
+func (d *Device) runAnimation(index uint8, count int, frame func(i int) image.Image, delay time.Duration, opts AnimationOptions, done chan struct{}) {
+	total := count * opts.Loops
+	start := time.Now()
+	frameStart := start
+	rateStart, shown := start, 1
+
+	for i := 0; ; {
+		// writing the frame already took up part of its delay, or all of it
+		// if the device is slower than the frame rate
+		wait := delay - time.Since(frameStart)
+		if wait < 0 {
+			wait = 0
+		}
+		select {
+		case <-time.After(wait):
+		case <-done:
+			return
+		}
+
+		if opts.SkipFrames {
+			next := int(time.Since(start) / delay)
+			if next <= i {
+				next = i + 1
+			}
+			// never skip the final frame, it stays on the button
+			if total > 0 && next >= total && i < total-1 {
+				next = total - 1
+			}
+			i = next
+		} else {
+			i++
+		}
+		if total > 0 && i >= total {
+			return
+		}
+
+		frameStart = time.Now()
+		last := total > 0 && i == total-1
+		if ok, err := d.setAnimationFrame(index, newKeyImage(frame(i%count)), done, last); !ok || err != nil {
+			return
+		}
+		shown++
+
+		if opts.OnFrameRate != nil {
+			if elapsed := time.Since(rateStart); elapsed >= time.Second {
+				opts.OnFrameRate(float64(shown) / elapsed.Seconds())
+				rateStart, shown = time.Now(), 0
+			}
+		}
+	}
+}
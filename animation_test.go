@@ -0,0 +1,135 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestBlinkInvalid(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	if err := d.Blink(0, color.White, -1, time.Millisecond); err == nil {
+		t.Error("negative blink count got accepted")
+	}
+	if err := d.Blink(0, color.White, 2, 0); err == nil {
+		t.Error("zero blink interval got accepted")
+	}
+	if err := d.Blink(0, color.White, 0, time.Millisecond); err != nil {
+		t.Errorf("blinking zero times failed: %v", err)
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sent %d commands, expected none", len(ops))
+	}
+}
+
+func TestBlink(t *testing.T) {
+	d, f := newTestDevice(t)
+	red := color.RGBA{255, 0, 0, 255}
+	if err := d.SetImage(0, d.solidImage(red)); err != nil {
+		t.Fatal(err)
+	}
+	f.Reset()
+
+	if err := d.Blink(0, color.White, 2, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.ImageWrites(0); n != 4 {
+		t.Errorf("got %d images, expected 4", n)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, red) {
+		t.Errorf("got color %v after blinking, expected the previous image", c)
+	}
+}
+
+func TestSetImageTTLRestoresBaseImage(t *testing.T) {
+	d, f := newTestDevice(t)
+	red := color.RGBA{255, 0, 0, 255}
+	if err := d.SetImage(0, d.solidImage(red)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second TTL must not restore the first TTL's transient image
+	if err := d.SetImageTTL(0, d.solidImage(color.RGBA{0, 255, 0, 255}), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImageTTL(0, d.solidImage(color.RGBA{0, 0, 255, 255}), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return f.ImageWrites(0) == 4
+	})
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, red) {
+		t.Errorf("got color %v after the TTL, expected the base image", c)
+	}
+}
+
+func TestBlinkDuringTTL(t *testing.T) {
+	d, f := newTestDevice(t)
+	red := color.RGBA{255, 0, 0, 255}
+	if err := d.SetImage(0, d.solidImage(red)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImageTTL(0, d.solidImage(color.RGBA{0, 255, 0, 255}), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Blink(0, color.White, 1, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, red) {
+		t.Errorf("got color %v after blinking, expected the base image", c)
+	}
+}
+
+func TestSetAnimationInvalid(t *testing.T) {
+	d, f := newTestDevice(t)
+	frames := []image.Image{d.solidImage(color.White)}
+
+	if err := d.SetAnimation(0, frames, time.Millisecond, AnimationOptions{Loops: -1}); err == nil {
+		t.Error("negative loop count got accepted")
+	}
+	if err := d.SetAnimation(0, frames, 0, AnimationOptions{}); err == nil {
+		t.Error("zero frame delay got accepted")
+	}
+	if err := d.SetAnimation(0, nil, time.Millisecond, AnimationOptions{}); err == nil {
+		t.Error("animation without frames got accepted")
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sent %d commands, expected none", len(ops))
+	}
+}
+
+func TestSkipFramesKeepsFinalFrame(t *testing.T) {
+	d, f := newTestDevice(t)
+	colors := []color.RGBA{
+		{255, 0, 0, 255},
+		{0, 255, 0, 255},
+		{0, 0, 255, 255},
+		{255, 255, 0, 255},
+		{0, 255, 255, 255},
+	}
+	final := colors[len(colors)-1]
+
+	// rendering a frame takes far longer than its delay, so the frames after
+	// the second one would all get skipped
+	_, err := d.startFrames(0, len(colors), func(i int) image.Image {
+		time.Sleep(20 * time.Millisecond)
+		return d.solidImage(colors[i])
+	}, time.Millisecond, AnimationOptions{Loops: 1, SkipFrames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		img, ok := d.images.Get(0)
+		return ok && near(img.img.At(36, 36), final)
+	})
+	if n := f.ImageWrites(0); n >= len(colors) {
+		t.Errorf("got %d images, expected frames to be skipped", n)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, final) {
+		t.Errorf("got color %v, expected the final frame", c)
+	}
+}
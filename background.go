@@ -0,0 +1,41 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+)
+
+// SetImageOnBackground sets an image with transparency on a button,
+// compositing it onto the given background color first.
+func (d *Device) SetImageOnBackground(index uint8, img image.Image, bg color.Color) error {
+	return d.SetImageOpt(index, img, WithBackground(bg))
+}
+
+// WithBackground composites images with transparency onto the given color,
+// as the device can't display transparency. Without a background, transparent
+// parts of images are shown black.
+func WithBackground(bg color.Color) ImageOption {
+	return func(o *imageOptions) {
+		o.background = color.RGBAModel.Convert(bg).(color.RGBA)
+	}
+}
+
+// flattenImage composites an image onto an opaque background color. Images
+// which are already opaque are returned as they are.
+func flattenImage(img image.Image, bg color.RGBA) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+
+	// the background itself must be opaque, or it would show black again, so
+	// its alpha is ignored
+	bg.A = 255
+
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, b, img, b.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,63 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// halfRed returns a key image filled with half-transparent red.
+func halfRed(d *Device) *image.NRGBA {
+	img := image.NewNRGBA(d.KeyBounds())
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.NRGBA{255, 0, 0, 128}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestFlattenImage(t *testing.T) {
+	d, _ := newTestDevice(t)
+
+	tt := []struct {
+		bg   color.RGBA
+		want color.RGBA
+	}{
+		{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 127, 127, 255}},
+		{color.RGBA{0, 0, 255, 255}, color.RGBA{128, 0, 127, 255}},
+		// the background's alpha gets ignored
+		{color.RGBA{0, 0, 0, 0}, color.RGBA{128, 0, 0, 255}},
+	}
+
+	for _, test := range tt {
+		img := flattenImage(halfRed(d), test.bg)
+		if c := color.RGBAModel.Convert(img.At(10, 10)); !near(c, test.want) {
+			t.Errorf("background %v: got %v, expected %v", test.bg, c, test.want)
+		}
+		if o, ok := img.(interface{ Opaque() bool }); !ok || !o.Opaque() {
+			t.Errorf("background %v: flattened image isn't opaque", test.bg)
+		}
+	}
+
+	opaque := d.solidImage(color.White)
+	if img := flattenImage(opaque, color.RGBA{}); img != image.Image(opaque) {
+		t.Error("opaque image got copied")
+	}
+}
+
+func TestSetImageOnBackground(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	if err := d.SetImageOnBackground(0, halfRed(d), color.White); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImage(1, halfRed(d)); err != nil {
+		t.Fatal(err)
+	}
+
+	// without a background, transparent parts are black
+	for index, want := range map[uint8]color.RGBA{0: {255, 127, 127, 255}, 1: {128, 0, 0, 255}} {
+		if c := f.LastImage(t, index).At(36, 36); !near(c, want) {
+			t.Errorf("key %d: got color %v, expected %v", index, c, want)
+		}
+	}
+}
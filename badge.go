@@ -0,0 +1,106 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// maxBadgeCount is the highest count shown on a badge, larger counts are shown
+// as "99+".
+const maxBadgeCount = 99
+
+// SetBadge draws a notification badge showing the given count in the top
+// right corner of a button, on top of the image currently set on it (or black
+// if there isn't one). A count of zero removes the badge again. Setting
+// another image on the button removes the badge as well.
+func (d *Device) SetBadge(index uint8, count int) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+	if count < 0 {
+		count = 0
+	}
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	ki, ok := d.images.Get(index)
+	if !ok {
+		ki = newKeyImage(d.solidImage(color.RGBA{0, 0, 0, 255}))
+	}
+	ki.opts.badge = count
+
+	d.stopAnimation(index)
+	return d.setImage(index, ki.img, ki.opts)
+}
+
+// drawBadge returns a copy of img with a badge showing count in its top right
+// corner. The label shrinks to fit the badge the more digits it has.
+func drawBadge(img image.Image, count int) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+
+	size := b.Dx()
+	if b.Dy() < size {
+		size = b.Dy()
+	}
+	diameter := float64(size) * 0.45
+	radius := diameter / 2
+	cx := float64(b.Max.X) - radius - float64(size)*0.03
+	cy := float64(b.Min.Y) + radius + float64(size)*0.03
+
+	red := color.RGBA{220, 30, 30, 255}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) <= radius {
+				dst.Set(x, y, red)
+			}
+		}
+	}
+
+	label := strconv.Itoa(count)
+	if count > maxBadgeCount {
+		label = strconv.Itoa(maxBadgeCount) + "+"
+	}
+	text := renderLabel(label)
+
+	// scale the label to fit into the circle, keeping its aspect ratio
+	tb := text.Bounds()
+	scale := math.Min(diameter*0.7/float64(tb.Dx()), diameter*0.6/float64(tb.Dy()))
+	w := int(math.Round(float64(tb.Dx()) * scale))
+	h := int(math.Round(float64(tb.Dy()) * scale))
+	x0 := int(math.Round(cx)) - w/2
+	y0 := int(math.Round(cy)) - h/2
+	draw.CatmullRom.Scale(dst, image.Rect(x0, y0, x0+w, y0+h), text, tb, draw.Over, nil)
+
+	return dst
+}
+
+// renderLabel renders text in white on a transparent background, using a
+// basic bitmap font.
+func renderLabel(s string) *image.RGBA {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, s).Ceil()
+	img := image.NewRGBA(image.Rect(0, 0, width, face.Height))
+
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	drawer.DrawString(s)
+	return img
+}
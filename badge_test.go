@@ -0,0 +1,73 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// litBounds returns the bounding box of the pixels for which lit is true.
+func litBounds(img image.Image, lit func(r, g, b uint32) bool) image.Rectangle {
+	var box image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); lit(r, g, b) {
+				box = box.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return box
+}
+
+func TestDrawBadge(t *testing.T) {
+	const size = 72
+	bg := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(bg, bg.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	var labels []image.Rectangle
+	for _, count := range []int{7, 42} {
+		img := drawBadge(bg, count)
+
+		// the badge is a circle 45% of the key wide, inset by 3% from the top
+		// right corner
+		badge := litBounds(img, func(r, g, b uint32) bool { return r|g|b != 0 })
+		want := image.Rect(38, 2, 70, 34)
+		if abs(badge.Min.X-want.Min.X) > 1 || abs(badge.Min.Y-want.Min.Y) > 1 ||
+			abs(badge.Max.X-want.Max.X) > 1 || abs(badge.Max.Y-want.Max.Y) > 1 {
+			t.Errorf("count %d: got badge at %v, expected %v", count, badge, want)
+		}
+
+		// the other corners stay untouched
+		for _, p := range []image.Point{{0, 0}, {0, size - 1}, {size - 1, size - 1}, {size / 4, size * 3 / 4}} {
+			if c := img.At(p.X, p.Y); !near(c, color.Black) {
+				t.Errorf("count %d: got color %v at %v, expected black", count, c, p)
+			}
+		}
+
+		// the label is white, unlike the red circle
+		label := litBounds(img, func(r, g, b uint32) bool { return g > 0x8000 })
+		if label.Empty() || !label.In(badge) {
+			t.Fatalf("count %d: got label at %v, expected it inside the badge %v", count, label, badge)
+		}
+		labels = append(labels, label)
+	}
+
+	// two digits are wider, but get shrunk to fit the badge
+	one, two := labels[0], labels[1]
+	if two.Dx() <= one.Dx() {
+		t.Errorf("got a label %d wide for two digits and %d for one, expected it wider", two.Dx(), one.Dx())
+	}
+	if two.Dy() > one.Dy() {
+		t.Errorf("got a label %d high for two digits and %d for one, expected it not higher", two.Dy(), one.Dy())
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package streamdeck
+
+import "sync"
+
+// brightnessHandler notifies about brightness changes.
+type brightnessHandler struct {
+	sync.Mutex
+	fn      func(from, to uint8)
+	perStep bool
+
+	// while held, changes get recorded and notified on release
+	held    int
+	changes []brightnessChange
+}
+
+// brightnessChange is a change of the brightness waiting to be notified.
+type brightnessChange struct {
+	from, to uint8
+}
+
+// Notify calls the handler function, if any, unless the brightness didn't
+// change. While notifications are held, the change gets recorded instead.
+func (h *brightnessHandler) Notify(from, to uint8) {
+	if from == to {
+		return
+	}
+
+	h.Lock()
+	if h.held > 0 {
+		h.changes = append(h.changes, brightnessChange{from, to})
+		h.Unlock()
+		return
+	}
+	fn := h.fn
+	h.Unlock()
+
+	if fn != nil {
+		fn(from, to)
+	}
+}
+
+// Hold defers notifications until Release gets called.
+func (h *brightnessHandler) Hold() {
+	h.Lock()
+	defer h.Unlock()
+
+	h.held++
+}
+
+// Release notifies the changes recorded since Hold, once the last hold got
+// released.
+func (h *brightnessHandler) Release() {
+	h.Lock()
+	h.held--
+	if h.held > 0 {
+		h.Unlock()
+		return
+	}
+	fn, changes := h.fn, h.changes
+	h.changes = nil
+	h.Unlock()
+
+	if fn == nil {
+		return
+	}
+	for _, c := range changes {
+		fn(c.from, c.to)
+	}
+}
+
+// PerStep returns true if every step of a fade should be notified.
+func (h *brightnessHandler) PerStep() bool {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.perStep
+}
+
+// OnBrightnessChange sets a function which gets called whenever the
+// brightness changes, no matter if by SetBrightness, a fade or sleep. Fades
+// only notify about their overall change, unless perStep is true, in which
+// case every step gets notified. The handler may call the device's methods,
+// e.g. to adjust the brightness. Passing nil removes the handler.
+func (d *Device) OnBrightnessChange(fn func(from, to uint8), perStep bool) {
+	d.brightnessHandler.Lock()
+	defer d.brightnessHandler.Unlock()
+
+	d.brightnessHandler.fn = fn
+	d.brightnessHandler.perStep = perStep
+}
+
+// Brightness returns the background lighting brightness in percent, as last
+// set by SetBrightness, a fade or sleep.
+func (d Device) Brightness() uint8 {
+	return d.brightness
+}
+
+// IncreaseBrightness raises the brightness by the given percentage points,
+// up to 100 percent. While the device is asleep, it raises the brightness the
+// device will wake up with.
+func (d *Device) IncreaseBrightness(step uint8) error {
+	current := d.targetBrightness()
+	if step > 100-current {
+		return d.SetBrightness(100)
+	}
+	return d.SetBrightness(current + step)
+}
+
+// DecreaseBrightness lowers the brightness by the given percentage points,
+// down to 0 percent. While the device is asleep, it lowers the brightness the
+// device will wake up with.
+func (d *Device) DecreaseBrightness(step uint8) error {
+	current := d.targetBrightness()
+	if step > current {
+		return d.SetBrightness(0)
+	}
+	return d.SetBrightness(current - step)
+}
+
+// targetBrightness returns the brightness the device has when it's awake.
+func (d *Device) targetBrightness() uint8 {
+	d.sleepMutex.RLock()
+	defer d.sleepMutex.RUnlock()
+
+	if d.asleep {
+		return d.preSleepBrightness
+	}
+	return d.brightness
+}
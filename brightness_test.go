@@ -0,0 +1,111 @@
+package streamdeck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrightnessSteps(t *testing.T) {
+	tt := []struct {
+		start uint8
+		step  int
+		want  uint8
+	}{
+		{50, 10, 60},
+		{50, -10, 40},
+		{95, 10, 100},
+		{100, 255, 100},
+		{5, -10, 0},
+		{0, -255, 0},
+		{0, 100, 100},
+	}
+
+	for _, test := range tt {
+		d, f := newTestDevice(t)
+		if err := d.SetBrightness(test.start); err != nil {
+			t.Fatal(err)
+		}
+
+		var err error
+		if test.step >= 0 {
+			err = d.IncreaseBrightness(uint8(test.step))
+		} else {
+			err = d.DecreaseBrightness(uint8(-test.step))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b := d.Brightness(); b != test.want {
+			t.Errorf("%d%+d: got brightness %d, expected %d", test.start, test.step, b, test.want)
+		}
+		if b := f.Brightnesses(); b[len(b)-1] != test.want {
+			t.Errorf("%d%+d: sent brightness %d, expected %d", test.start, test.step, b[len(b)-1], test.want)
+		}
+	}
+}
+
+func TestBrightnessAfterOpen(t *testing.T) {
+	d, _ := newTestDevice(t)
+	if b := d.Brightness(); b != 100 {
+		t.Errorf("got brightness %d after Open, expected 100", b)
+	}
+
+	// sleeping right after Open fades out from full brightness
+	d.SetClock(&fakeClock{})
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	if b := d.Brightness(); b != 100 {
+		t.Errorf("got brightness %d after waking, expected 100", b)
+	}
+}
+
+func TestBrightnessHandlerDuringSleep(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetSleepFadeDuration(0)
+	if err := d.SetBrightness(50); err != nil {
+		t.Fatal(err)
+	}
+
+	// the handler calls methods which take the sleep lock
+	var handlerErr error
+	var changes int
+	d.OnBrightnessChange(func(from, to uint8) {
+		changes++
+		if to == 0 {
+			d.NotifyActivity()
+			handlerErr = d.IncreaseBrightness(10)
+		}
+	}, false)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Sleep()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Sleep deadlocked calling the brightness handler")
+	}
+	if handlerErr != nil {
+		t.Fatal(handlerErr)
+	}
+	if changes == 0 {
+		t.Fatal("brightness handler didn't get called")
+	}
+
+	// the increase applies to the brightness the device wakes up with
+	if err := d.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	if b := f.Brightnesses(); b[len(b)-1] != 60 {
+		t.Errorf("woke up with brightness %d, expected 60", b[len(b)-1])
+	}
+}
@@ -0,0 +1,231 @@
+package streamdeck
+
+import (
+	"bytes"
+	"container/list"
+	"image"
+	"image/png"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+// keyImage is an image shown on a key, along with the options it was set with.
+type keyImage struct {
+	img  image.Image
+	opts imageOptions
+}
+
+// newKeyImage returns a keyImage using the default image options.
+func newKeyImage(img image.Image) keyImage {
+	return keyImage{
+		img:  img,
+		opts: defaultImageOptions(),
+	}
+}
+
+// cachedImage is a keyImage in the compact form kept by the imageCache: the
+// image gets stored PNG-encoded, which takes a fraction of the memory of the
+// decoded pixels and doesn't change when the caller changes the image later
+// on.
+type cachedImage struct {
+	data   []byte
+	bounds image.Rectangle
+	opts   imageOptions
+
+	// img holds images which can't be encoded, e.g. empty ones
+	img image.Image
+}
+
+// newCachedImage encodes a keyImage for the imageCache.
+func newCachedImage(ki keyImage) cachedImage {
+	ci := cachedImage{
+		bounds: ki.img.Bounds(),
+		opts:   ki.opts,
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&buf, ki.img); err != nil {
+		ci.img = ki.img
+		return ci
+	}
+	ci.data = buf.Bytes()
+	return ci
+}
+
+// keyImage decodes the cached image.
+func (ci cachedImage) keyImage() keyImage {
+	if ci.img != nil {
+		return keyImage{img: ci.img, opts: ci.opts}
+	}
+
+	img, err := png.Decode(bytes.NewReader(ci.data))
+	if err != nil {
+		// can't happen, the data got encoded by newCachedImage
+		img = image.NewRGBA(ci.bounds)
+	}
+	// PNG images always start at the origin
+	if ci.bounds.Min != (image.Point{}) {
+		out := image.NewRGBA(ci.bounds)
+		draw.Draw(out, ci.bounds, img, image.Point{}, draw.Src)
+		img = out
+	}
+	return keyImage{img: img, opts: ci.opts}
+}
+
+// cacheEntry is an element of the imageCache's LRU list.
+type cacheEntry struct {
+	index uint8
+	image cachedImage
+}
+
+// imageCache keeps track of the images currently shown on the keys. It can be
+// limited to a number of images, in which case the least recently used ones
+// get evicted. Images are stored encoded, see cachedImage, and get decoded
+// into a new image every time they are read.
+type imageCache struct {
+	sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[uint8]*list.Element
+}
+
+// newImageCache returns an empty image cache without a size limit.
+func newImageCache() *imageCache {
+	return &imageCache{
+		lru:     list.New(),
+		entries: make(map[uint8]*list.Element),
+	}
+}
+
+// Get returns the cached image of a key and whether there was one.
+func (c *imageCache) Get(index uint8) (keyImage, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[index]
+	if !ok {
+		return keyImage{}, false
+	}
+
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).image.keyImage(), true
+}
+
+// Peek returns the cached image of a key like Get does, without marking it as
+// recently used.
+func (c *imageCache) Peek(index uint8) (keyImage, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[index]
+	if !ok {
+		return keyImage{}, false
+	}
+	return e.Value.(*cacheEntry).image.keyImage(), true
+}
+
+// PeekCached returns the cached image of a key in its encoded form, without
+// marking it as recently used.
+func (c *imageCache) PeekCached(index uint8) (cachedImage, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[index]
+	if !ok {
+		return cachedImage{}, false
+	}
+	return e.Value.(*cacheEntry).image, true
+}
+
+// Set caches the image of a key.
+func (c *imageCache) Set(index uint8, ki keyImage) {
+	c.SetCached(index, newCachedImage(ki))
+}
+
+// SetCached caches an already encoded image of a key, e.g. to share it among
+// several keys.
+func (c *imageCache) SetCached(index uint8, ci cachedImage) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.entries[index]; ok {
+		e.Value.(*cacheEntry).image = ci
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[index] = c.lru.PushFront(&cacheEntry{index: index, image: ci})
+	c.evict()
+}
+
+// Delete removes the cached image of a key.
+func (c *imageCache) Delete(index uint8) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.entries[index]; ok {
+		c.lru.Remove(e)
+		delete(c.entries, index)
+	}
+}
+
+// Clear removes all cached images.
+func (c *imageCache) Clear() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.lru.Init()
+	c.entries = make(map[uint8]*list.Element)
+}
+
+// SetSize limits the cache to the given number of images. Zero means no
+// limit.
+func (c *imageCache) SetSize(n int) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.size = n
+	c.evict()
+}
+
+// evict removes the least recently used images until the cache is within its
+// size limit. The caller must hold the lock.
+func (c *imageCache) evict() {
+	for c.size > 0 && c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*cacheEntry).index)
+	}
+}
+
+// SetImageCacheSize limits how many button images the device keeps cached for
+// restoring them, e.g. when waking up from a blanked sleep or after a Blink.
+// The least recently used images get evicted first; buttons without a cached
+// image get restored to black. Zero, the default, means no limit.
+func (d *Device) SetImageCacheSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	d.images.SetSize(n)
+}
+
+// Thumbnail returns a copy of the cached image of a button, scaled down to a
+// square of the given size, e.g. for previews in a deck editor. It returns
+// false if no image is cached for the button.
+func (d Device) Thumbnail(index uint8, size int) (image.Image, bool) {
+	ki, ok := d.images.Peek(index)
+	if !ok || size <= 0 {
+		return nil, false
+	}
+
+	img := ki.img
+	if ki.opts.badge > 0 {
+		img = drawBadge(img, ki.opts.badge)
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.ApproxBiLinear.Scale(thumb, thumb.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return thumb, true
+}
@@ -0,0 +1,94 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+func TestImageCacheEviction(t *testing.T) {
+	c := newImageCache()
+	c.SetSize(2)
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+	c.Set(0, newKeyImage(img))
+	c.Set(1, newKeyImage(img))
+	// reading key 0 makes key 1 the least recently used image
+	if _, ok := c.Get(0); !ok {
+		t.Fatal("key 0 didn't get cached")
+	}
+	c.Set(2, newKeyImage(img))
+
+	for index, want := range map[uint8]bool{0: true, 1: false, 2: true} {
+		if _, ok := c.Peek(index); ok != want {
+			t.Errorf("key %d: got cached %v, expected %v", index, ok, want)
+		}
+	}
+
+	// peeking doesn't count as using an image
+	c.Peek(0)
+	c.SetSize(1)
+	if _, ok := c.Peek(2); !ok {
+		t.Error("shrinking the cache evicted the most recently used image")
+	}
+	if _, ok := c.Peek(0); ok {
+		t.Error("shrinking the cache kept the least recently used image")
+	}
+}
+
+func TestImageCacheEncodes(t *testing.T) {
+	c := newImageCache()
+	red := color.RGBA{255, 0, 0, 255}
+	img := image.NewRGBA(image.Rect(10, 10, 82, 82))
+	draw.Draw(img, img.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+	opts := defaultImageOptions()
+	opts.badge = 3
+	c.Set(0, keyImage{img: img, opts: opts})
+
+	ci, _ := c.PeekCached(0)
+	if ci.img != nil || len(ci.data) == 0 || len(ci.data) >= len(img.Pix) {
+		t.Errorf("got a cached image of %d bytes, expected it to be encoded", len(ci.data))
+	}
+
+	// changing the image afterwards doesn't change the cached one
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	ki, _ := c.Get(0)
+	if ki.img.Bounds() != img.Bounds() {
+		t.Errorf("got a cached image with bounds %v, expected %v", ki.img.Bounds(), img.Bounds())
+	}
+	if c := ki.img.At(40, 40); c != color.Color(red) {
+		t.Errorf("got cached color %v, expected red", c)
+	}
+	if ki.opts != opts {
+		t.Errorf("got cached options %+v, expected %+v", ki.opts, opts)
+	}
+}
+
+func TestSetImageCacheSize(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetImageCacheSize(2)
+	d.SetSleepMode(Blank)
+	d.SetSleepFadeDuration(0)
+
+	red := color.RGBA{255, 0, 0, 255}
+	for i := uint8(0); i < 3; i++ {
+		if err := d.SetImage(i, d.solidImage(red)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Wake(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the evicted image of key 0 gets restored to black
+	for index, want := range map[uint8]color.RGBA{0: {0, 0, 0, 255}, 1: red, 2: red} {
+		if c := f.LastImage(t, index).At(36, 36); !near(c, want) {
+			t.Errorf("key %d: got color %v after waking, expected %v", index, c, want)
+		}
+	}
+}
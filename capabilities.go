@@ -0,0 +1,31 @@
+package streamdeck
+
+// Capability is a set of optional features a device supports.
+type Capability uint
+
+// Device capabilities.
+const (
+	// CapLogoScreen devices can show a standby logo.
+	CapLogoScreen Capability = 1 << iota
+	// CapEncoders devices have rotary encoders (knobs).
+	CapEncoders
+	// CapTouchStrip devices have a touch strip with its own screen.
+	CapTouchStrip
+	// CapReadBrightness devices can report their current brightness.
+	CapReadBrightness
+	// CapStandbyTimeout devices have a configurable firmware standby timeout.
+	CapStandbyTimeout
+	// CapProfiles devices store profiles which the firmware can switch
+	// between.
+	CapProfiles
+)
+
+// Has returns true if all the given capabilities are part of the set.
+func (c Capability) Has(caps Capability) bool {
+	return c&caps == caps
+}
+
+// Capabilities returns the set of optional features the device supports.
+func (d Device) Capabilities() Capability {
+	return d.capabilities
+}
@@ -0,0 +1,28 @@
+package streamdeck
+
+import "time"
+
+// Clock lets fade animations and retries wait. Replacing the default clock
+// allows running them instantly, e.g. in tests.
+type Clock interface {
+	Sleep(d time.Duration)
+}
+
+// realClock is the default Clock, using the system time.
+type realClock struct{}
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// SetClock replaces the clock used by fade animations and retries. Passing
+// nil restores the system clock.
+func (d *Device) SetClock(c Clock) {
+	d.clock = c
+}
+
+// getClock returns the clock used by fade animations and retries.
+func (d Device) getClock() Clock {
+	if d.clock == nil {
+		return realClock{}
+	}
+	return d.clock
+}
@@ -0,0 +1,44 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// SetKeyColorHex fills a button with a color given as a hex string in the
+// form #RGB, #RRGGBB or #RRGGBBAA. The leading # is optional.
+func (d *Device) SetKeyColorHex(index uint8, hex string) error {
+	c, err := parseHexColor(hex)
+	if err != nil {
+		return err
+	}
+	return d.SetImage(index, d.solidImage(c))
+}
+
+// parseHexColor parses a color in the form #RGB, #RRGGBB or #RRGGBBAA.
+func parseHexColor(hex string) (color.Color, error) {
+	s := strings.TrimPrefix(hex, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return nil, fmt.Errorf("invalid hex color %q: expected #RGB, #RRGGBB or #RRGGBBAA", hex)
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex color %q: %v", hex, err)
+	}
+
+	return color.NRGBA{
+		R: uint8(v >> 24),
+		G: uint8(v >> 16),
+		B: uint8(v >> 8),
+		A: uint8(v),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package streamdeck
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tt := []struct {
+		hex  string
+		want color.NRGBA
+	}{
+		{"#ff8000", color.NRGBA{0xff, 0x80, 0x00, 0xff}},
+		{"ff8000", color.NRGBA{0xff, 0x80, 0x00, 0xff}},
+		{"#F80", color.NRGBA{0xff, 0x88, 0x00, 0xff}},
+		{"#abc", color.NRGBA{0xaa, 0xbb, 0xcc, 0xff}},
+		{"#ff800080", color.NRGBA{0xff, 0x80, 0x00, 0x80}},
+		{"#00000000", color.NRGBA{}},
+	}
+
+	for _, test := range tt {
+		c, err := parseHexColor(test.hex)
+		if err != nil {
+			t.Errorf("%s: %v", test.hex, err)
+			continue
+		}
+		if c != test.want {
+			t.Errorf("%s: got %v, expected %v", test.hex, c, test.want)
+		}
+	}
+}
+
+func TestParseHexColorInvalid(t *testing.T) {
+	for _, hex := range []string{"", "#", "#ff", "#ff80", "#ff8000f", "#gg8000", "#ff80 0", "##ff8000", "#+f8000"} {
+		if c, err := parseHexColor(hex); err == nil {
+			t.Errorf("%q: got %v, expected an error", hex, c)
+		}
+	}
+}
+
+func TestSetKeyColorHex(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	if err := d.SetKeyColorHex(0, "#f00"); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("got color %v, expected red", c)
+	}
+	if err := d.SetKeyColorHex(0, "red"); err == nil {
+		t.Error("color name got accepted as a hex color")
+	}
+}
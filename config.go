@@ -0,0 +1,91 @@
+package streamdeck
+
+import "time"
+
+// Config holds the tunables of a device, so they can be set up at once rather
+// than through individual setters. The zero value keeps the defaults.
+//
+// A Config gets applied when the device is opened. Setters called after
+// opening the device take precedence, until the device gets opened again.
+type Config struct {
+	// SleepMode is what the device does when put to sleep, see SetSleepMode.
+	SleepMode SleepMode
+	// SleepTimeout puts the device to sleep after this long without key
+	// events, see SetSleepTimeout. Zero disables it.
+	SleepTimeout time.Duration
+	// SleepFadeDuration is the duration of the fade when the device goes to
+	// sleep or wakes up, see SetSleepFadeDuration.
+	SleepFadeDuration time.Duration
+	// FadeDelay is the interval between two brightness steps of a fade, see
+	// SetFadeDelay. Zero uses the default.
+	FadeDelay time.Duration
+	// RetryDelay is the delay before retrying a verified command for the
+	// first time, see SetRetryDelay. Zero uses the default.
+	RetryDelay time.Duration
+	// CommandTimeouts sets how long to wait for each type of command, see
+	// SetCommandTimeout.
+	CommandTimeouts map[string]time.Duration
+	// VerifyBrightness enables verifying brightness changes, see
+	// SetBrightnessVerify.
+	VerifyBrightness bool
+	// ImageCacheSize limits how many button images are kept cached, see
+	// SetImageCacheSize. Zero means no limit.
+	ImageCacheSize int
+	// Clock is the clock used by fade animations and retries, see SetClock.
+	// Nil uses the system clock.
+	Clock Clock
+}
+
+// DevicesWithConfig works like Devices, but returns devices which get
+// configured with c when they are opened.
+func DevicesWithConfig(c Config) ([]Device, error) {
+	devs, err := Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range devs {
+		devs[i].config = c
+	}
+	return devs, nil
+}
+
+// SetConfig sets the configuration which gets applied the next time the
+// device is opened.
+func (d *Device) SetConfig(c Config) {
+	d.config = c
+}
+
+// applyConfig applies the configuration to a device which just got opened.
+// Unset fields leave the device's settings alone.
+func (d *Device) applyConfig() {
+	c := d.config
+
+	if c.SleepMode != DimOnly {
+		d.SetSleepMode(c.SleepMode)
+	}
+	if c.SleepFadeDuration > 0 {
+		d.SetSleepFadeDuration(c.SleepFadeDuration)
+	}
+	if c.FadeDelay > 0 {
+		d.SetFadeDelay(c.FadeDelay)
+	}
+	if c.RetryDelay > 0 {
+		d.SetRetryDelay(c.RetryDelay)
+	}
+	for cmd, t := range c.CommandTimeouts {
+		d.SetCommandTimeout(cmd, t)
+	}
+	if c.VerifyBrightness {
+		d.SetBrightnessVerify(true)
+	}
+	if c.ImageCacheSize > 0 {
+		d.SetImageCacheSize(c.ImageCacheSize)
+	}
+	if c.Clock != nil {
+		d.SetClock(c.Clock)
+	}
+	if c.SleepTimeout > 0 {
+		d.SetSleepTimeout(c.SleepTimeout)
+	}
+}
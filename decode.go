@@ -0,0 +1,26 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"io"
+
+	// image formats supported by SetImageFromReader
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	// decoding only, golang.org/x/image has no WebP encoder
+	_ "golang.org/x/image/webp"
+)
+
+// SetImageFromReader decodes a GIF, JPEG, PNG or WebP image and sets it on a
+// button, scaling it to the device's key resolution.
+func (d *Device) SetImageFromReader(index uint8, r io.Reader) error {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return fmt.Errorf("cannot decode image: %w", err)
+	}
+
+	return d.SetImageOpt(index, img, WithFit(Stretch))
+}
@@ -0,0 +1,57 @@
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// redWebP is a lossless 4x4 WebP image, filled with opaque red.
+var redWebP = []byte{
+	0x52, 0x49, 0x46, 0x46, 0x18, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50,
+	0x56, 0x50, 0x38, 0x4c, 0x0c, 0x00, 0x00, 0x00, 0x2f, 0x03, 0xc0, 0x00,
+	0x00, 0x28, 0x40, 0xff, 0x0b, 0xd0, 0xff, 0x00,
+}
+
+func TestDecodeWebP(t *testing.T) {
+	img, format, err := image.Decode(bytes.NewReader(redWebP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "webp" {
+		t.Errorf("got format %q, expected webp", format)
+	}
+	if b := img.Bounds(); b != image.Rect(0, 0, 4, 4) {
+		t.Errorf("got bounds %v, expected 4x4", b)
+	}
+	if c := img.At(1, 2); !near(c, color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("got color %v, expected red", c)
+	}
+}
+
+func TestSetImageFromReaderWebP(t *testing.T) {
+	d, f := newTestDevice(t)
+	if err := d.SetImageFromReader(0, bytes.NewReader(redWebP)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tiny image gets stretched across the whole key
+	img := f.LastImage(t, 0)
+	for _, p := range []image.Point{{2, 2}, {36, 36}, {69, 69}} {
+		if c := img.At(p.X, p.Y); !near(c, color.RGBA{255, 0, 0, 255}) {
+			t.Errorf("got color %v at %v, expected red", c, p)
+		}
+	}
+}
+
+func TestSetImageFromReaderInvalid(t *testing.T) {
+	d, f := newTestDevice(t)
+	// a WebP header without any image data
+	if err := d.SetImageFromReader(0, bytes.NewReader(redWebP[:16])); err == nil {
+		t.Error("expected a truncated WebP image to fail")
+	}
+	if n := len(f.Images()); n != 0 {
+		t.Errorf("got %d images, expected none", n)
+	}
+}
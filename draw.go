@@ -0,0 +1,54 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+)
+
+// DrawKey creates a blank image in the device's key resolution, lets fn draw
+// onto it and sets it on the button. For example, to draw a progress bar:
+//
+//	d.DrawKey(0, func(img draw.Image) {
+//		b := img.Bounds()
+//		bar := image.Rect(0, b.Dy()*3/4, b.Dx()*progress/100, b.Dy())
+//		draw.Draw(img, bar, image.NewUniform(color.RGBA{0, 255, 0, 255}), image.Point{}, draw.Src)
+//	})
+func (d *Device) DrawKey(index uint8, fn func(img draw.Image)) error {
+	img := d.NewKeyImage()
+	fn(img)
+	return d.SetImage(index, img)
+}
+
+// NewKeyImage returns a blank image in the device's key resolution, ready to
+// be drawn on and passed to SetImage.
+func (d Device) NewKeyImage() *image.RGBA {
+	return image.NewRGBA(d.KeyBounds())
+}
+
+// KeyBounds returns the bounds images set on the device's buttons need to
+// have.
+func (d Device) KeyBounds() image.Rectangle {
+	return image.Rect(0, 0, int(d.Pixels), int(d.Pixels))
+}
+
+// KeyColorModel returns the color model of the device's button images. Images
+// in other color models get converted when they are sent to the device.
+func (d Device) KeyColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Layout returns the number of rows and columns of the device's buttons.
+func (d Device) Layout() (rows, columns uint8) {
+	return d.Rows, d.Columns
+}
+
+// PhysicalResolution returns the resolution of the device's whole faceplate,
+// including the gaps between the buttons. Use it to design artwork for
+// RenderToDeck.
+func (d Device) PhysicalResolution() (width, height int) {
+	width = int(d.Columns)*int(d.Pixels) + (int(d.Columns)-1)*int(d.Padding)
+	height = int(d.Rows)*int(d.Pixels) + (int(d.Rows)-1)*int(d.Padding)
+	return width, height
+}
@@ -0,0 +1,67 @@
+package streamdeck
+
+// Direction of an encoder rotation.
+type Direction int
+
+// Encoder rotation directions.
+const (
+	Clockwise Direction = iota
+	CounterClockwise
+)
+
+// Encoder holds the current status of a rotary encoder (knob) on the device.
+type Encoder struct {
+	Index     uint8
+	Direction Direction
+	Steps     uint8
+	Pressed   bool
+}
+
+// ReadEncoders returns a channel, which it will use to emit encoder rotations
+// and presses. Encoder events are read by the same loop as key events, so
+// ReadKeys needs to be called as well. It returns ErrUnsupported for devices
+// without encoders. Once requested, the channel must be drained continuously,
+// otherwise it blocks the delivery of key events as well.
+func (d *Device) ReadEncoders() (chan Encoder, error) {
+	if !d.Capabilities().Has(CapEncoders) {
+		return nil, ErrUnsupported
+	}
+
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	if d.encoderEvents == nil {
+		d.encoderEvents = make(chan Encoder)
+	}
+	return d.encoderEvents, nil
+}
+
+// emitEncoders parses the encoder events of a report and sends them to the
+// channel returned by ReadEncoders.
+func (d *Device) emitEncoders(report []byte) {
+	if d.parseEncoderReport == nil {
+		return
+	}
+
+	d.inputMutex.Lock()
+	ech := d.encoderEvents
+	d.inputMutex.Unlock()
+	if ech == nil {
+		return
+	}
+
+	for _, e := range d.parseEncoderReport(d, report) {
+		ech <- e
+	}
+}
+
+// closeEncoders closes the channel returned by ReadEncoders.
+func (d *Device) closeEncoders() {
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	if d.encoderEvents != nil {
+		close(d.encoderEvents)
+		d.encoderEvents = nil
+	}
+}
@@ -0,0 +1,50 @@
+package streamdeck
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned by methods the device doesn't support, e.g.
+// reading encoders on a device without knobs. Callers can check for it with
+// errors.Is.
+var ErrUnsupported = errors.New("not supported by this device")
+
+// DimensionError is returned when an image doesn't have the resolution of the
+// device's buttons.
+type DimensionError struct {
+	Width    int
+	Height   int
+	Expected uint
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("supplied image has wrong dimensions, expected %[1]dx%[1]d pixels", e.Expected)
+}
+
+// ErrClosed is returned for commands which are sent while the device is being
+// closed.
+var ErrClosed = errors.New("device is closed")
+
+// ErrNotOpen is returned when sending commands to a device before Open has
+// been called, or after Close.
+var ErrNotOpen = errors.New("device is not open")
+
+// ErrAlreadyOpen is returned by Open for a device which is open already.
+var ErrAlreadyOpen = errors.New("device is already open")
+
+// ErrTimeout is returned when the device didn't complete a command within the
+// timeout set with SetCommandTimeout.
+var ErrTimeout = errors.New("command timed out")
+
+// ErrAlreadyReading is returned by ReadKeys while a previously returned
+// channel is still being served.
+var ErrAlreadyReading = errors.New("keys are already being read")
+
+// ErrKeyOutOfRange is returned when a key index or position doesn't refer to
+// one of the device's buttons. Callers can check for it with errors.Is.
+var ErrKeyOutOfRange = errors.New("key out of range")
+
+// ErrImageTooLarge is returned by SetImageFromURL when the fetched image
+// exceeds the download limit.
+var ErrImageTooLarge = errors.New("image too large")
@@ -0,0 +1,284 @@
+package streamdeck
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/karalabe/hid"
+)
+
+// errFakeClosed is returned by a fakeHIDDevice once it has been closed.
+var errFakeClosed = errors.New("fake HID device closed")
+
+// fakeOp is a command sent to a fakeHIDDevice.
+type fakeOp struct {
+	feature bool
+	data    []byte
+}
+
+// fakeHIDDevice is a HIDDevice for testing without hardware. Read returns the
+// scripted input reports, GetFeatureReport the scripted feature reports, and
+// everything written to the device gets recorded.
+type fakeHIDDevice struct {
+	mutex    sync.Mutex
+	ops      []fakeOp
+	features map[byte][]byte
+	sendErrs []error
+	closes   int
+
+	// active counts the calls in progress, overlaps the calls which started
+	// while another one was still in progress
+	active   int32
+	overlaps int32
+
+	reports chan []byte
+	readErr error
+	done    chan struct{}
+	once    sync.Once
+}
+
+// newFakeHIDDevice returns a fake HID device without any scripted reports.
+func newFakeHIDDevice() *fakeHIDDevice {
+	return &fakeHIDDevice{
+		features: make(map[byte][]byte),
+		reports:  make(chan []byte, 16),
+		done:     make(chan struct{}),
+	}
+}
+
+// newTestDevice returns an opened Stream Deck MK.2 talking to a fake HID
+// device, which gets closed at the end of the test.
+func newTestDevice(t testing.TB) (*Device, *fakeHIDDevice) {
+	t.Helper()
+
+	f := newFakeHIDDevice()
+	d, err := NewDeviceWithHID(testDeviceInfo(PID_STREAMDECK_MK2), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = d.Close()
+	})
+	return d, f
+}
+
+// testDeviceInfo returns the HID device info of a fake device with the given
+// product ID.
+func testDeviceInfo(pid uint16) hid.DeviceInfo {
+	return hid.DeviceInfo{
+		Path:      "fake",
+		VendorID:  VID_ELGATO,
+		ProductID: pid,
+		Serial:    "FAKE0001",
+	}
+}
+
+// Report scripts an input report for Read to return.
+func (f *fakeHIDDevice) Report(report []byte) {
+	f.reports <- report
+}
+
+// Disconnect makes Read fail with err once all scripted reports were read.
+func (f *fakeHIDDevice) Disconnect(err error) {
+	f.mutex.Lock()
+	f.readErr = err
+	f.mutex.Unlock()
+	close(f.reports)
+}
+
+// SetFeature scripts the feature report returned for the given report ID.
+func (f *fakeHIDDevice) SetFeature(report []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.features[report[0]] = report
+}
+
+// FailFeatures makes the next calls of SendFeatureReport fail with the given
+// errors, one per call.
+func (f *fakeHIDDevice) FailFeatures(errs ...error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.sendErrs = append(f.sendErrs, errs...)
+}
+
+func (f *fakeHIDDevice) Read(b []byte) (int, error) {
+	select {
+	case r, ok := <-f.reports:
+		if !ok {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			return 0, f.readErr
+		}
+		return copy(b, r), nil
+	case <-f.done:
+		return 0, errFakeClosed
+	}
+}
+
+// enter records the start of a call writing to the device, detecting calls
+// which overlap. The returned function records its end.
+func (f *fakeHIDDevice) enter() func() {
+	if atomic.AddInt32(&f.active, 1) > 1 {
+		atomic.AddInt32(&f.overlaps, 1)
+	}
+	// give overlapping calls a chance to show up
+	runtime.Gosched()
+	return func() {
+		atomic.AddInt32(&f.active, -1)
+	}
+}
+
+// Overlaps returns how many calls overlapped with another one.
+func (f *fakeHIDDevice) Overlaps() int {
+	return int(atomic.LoadInt32(&f.overlaps))
+}
+
+func (f *fakeHIDDevice) Write(b []byte) (int, error) {
+	defer f.enter()()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closes > 0 {
+		return 0, errFakeClosed
+	}
+	f.ops = append(f.ops, fakeOp{data: append([]byte(nil), b...)})
+	return len(b), nil
+}
+
+func (f *fakeHIDDevice) GetFeatureReport(b []byte) (int, error) {
+	defer f.enter()()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closes > 0 {
+		return 0, errFakeClosed
+	}
+	return copy(b, f.features[b[0]]), nil
+}
+
+func (f *fakeHIDDevice) SendFeatureReport(b []byte) (int, error) {
+	defer f.enter()()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closes > 0 {
+		return 0, errFakeClosed
+	}
+	if len(f.sendErrs) > 0 {
+		err := f.sendErrs[0]
+		f.sendErrs = f.sendErrs[1:]
+		if err != nil {
+			return 0, err
+		}
+	}
+	f.ops = append(f.ops, fakeOp{feature: true, data: append([]byte(nil), b...)})
+	return len(b), nil
+}
+
+func (f *fakeHIDDevice) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.closes++
+	f.once.Do(func() {
+		close(f.done)
+	})
+	return nil
+}
+
+// Ops returns the commands sent to the device so far.
+func (f *fakeHIDDevice) Ops() []fakeOp {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]fakeOp(nil), f.ops...)
+}
+
+// Reset forgets the commands sent to the device so far.
+func (f *fakeHIDDevice) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.ops = nil
+}
+
+// Brightnesses returns the brightness values sent to the device, in order.
+func (f *fakeHIDDevice) Brightnesses() []uint8 {
+	var values []uint8
+	for _, op := range f.Ops() {
+		if op.feature && bytes.HasPrefix(op.data, c_REV2_BRIGHTNESS) {
+			values = append(values, op.data[len(c_REV2_BRIGHTNESS)])
+		}
+	}
+	return values
+}
+
+// fakeImage is an image sent to a key of the device.
+type fakeImage struct {
+	key  uint8
+	data []byte
+}
+
+// Images reassembles the images sent to the device from their pages, in the
+// order they were completed. It expects the page layout of the Stream Deck
+// MK.2.
+func (f *fakeHIDDevice) Images() []fakeImage {
+	var images []fakeImage
+	pending := make(map[uint8][]byte)
+	for _, op := range f.Ops() {
+		if op.feature || len(op.data) < 8 || op.data[0] != 0x02 || op.data[1] != 0x07 {
+			continue
+		}
+
+		key, last := op.data[2], op.data[3] == 1
+		length := int(op.data[4]) | int(op.data[5])<<8
+		pending[key] = append(pending[key], op.data[8:8+length]...)
+		if last {
+			images = append(images, fakeImage{key: key, data: pending[key]})
+			delete(pending, key)
+		}
+	}
+	return images
+}
+
+// ImageWrites returns how many images were sent to the given key.
+func (f *fakeHIDDevice) ImageWrites(key uint8) int {
+	var n int
+	for _, img := range f.Images() {
+		if img.key == key {
+			n++
+		}
+	}
+	return n
+}
+
+// LastImage decodes the image sent to the given key most recently, or returns
+// nil if none was sent.
+func (f *fakeHIDDevice) LastImage(t testing.TB, key uint8) image.Image {
+	t.Helper()
+
+	images := f.Images()
+	for i := len(images) - 1; i >= 0; i-- {
+		if images[i].key != key {
+			continue
+		}
+
+		img, err := jpeg.Decode(bytes.NewReader(images[i].data))
+		if err != nil {
+			t.Fatalf("cannot decode image of key %d: %v", key, err)
+		}
+		return img
+	}
+	return nil
+}
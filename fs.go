@@ -0,0 +1,26 @@
+//go:build go1.16
+// +build go1.16
+
+package streamdeck
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// SetImageFromFS reads an image from a file system, such as an embed.FS, and
+// sets it on a button like SetImageFromReader does:
+//
+//	//go:embed icons
+//	var icons embed.FS
+//
+//	err := d.SetImageFromFS(0, icons, "icons/play.png")
+func (d *Device) SetImageFromFS(index uint8, fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return fmt.Errorf("cannot open image %s: %w", name, err)
+	}
+	defer f.Close() //nolint:errcheck // r/o file
+
+	return d.SetImageFromReader(index, f)
+}
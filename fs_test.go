@@ -0,0 +1,32 @@
+//go:build go1.16
+// +build go1.16
+
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSetImageFromFSBeforeOpen(t *testing.T) {
+	var data bytes.Buffer
+	if err := png.Encode(&data, image.NewRGBA(image.Rect(0, 0, 72, 72))); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"icon.png": {Data: data.Bytes()}}
+
+	f := newFakeHIDDevice()
+	d, err := NewDeviceWithHID(testDeviceInfo(PID_STREAMDECK_MK2), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImageFromFS(0, fsys, "icon.png"); err != ErrNotOpen {
+		t.Errorf("got error %v, expected ErrNotOpen", err)
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sent %d commands to a device which isn't open", len(ops))
+	}
+}
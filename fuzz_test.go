@@ -0,0 +1,37 @@
+//go:build go1.18
+// +build go1.18
+
+package streamdeck
+
+import "testing"
+
+func FuzzParseKeyReport(f *testing.F) {
+	f.Add(uint16(PID_STREAMDECK_MK2), []byte{0x01, 0x00, 0x0f, 0x00, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+	f.Add(uint16(PID_STREAMDECK), []byte{0x01, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+	f.Add(uint16(PID_STREAMDECK_XL), make([]byte, 512))
+	f.Add(uint16(PID_STREAMDECK_MINI), []byte{})
+
+	f.Fuzz(func(t *testing.T, pid uint16, report []byte) {
+		d, ok := newDevice(testDeviceInfo(pid))
+		if !ok {
+			return
+		}
+
+		// parse the report twice, the second time against the key states
+		// the first one left behind
+		for i := 0; i < 2; i++ {
+			keys := d.parseKeyReport(&d, report)
+			checkKeys(t, &d, keys)
+			seen := make(map[uint8]bool)
+			for _, k := range keys {
+				if seen[k.Index] {
+					t.Fatalf("got key %d twice in a single report", k.Index)
+				}
+				seen[k.Index] = true
+			}
+			if i == 1 && len(keys) > 0 {
+				t.Fatalf("got %v for a repeated report, expected no changes", keys)
+			}
+		}
+	})
+}
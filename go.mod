@@ -1,10 +1,19 @@
 module github.com/muesli/streamdeck
 
-go 1.14
+go 1.17
 
 require (
 	github.com/karalabe/hid v1.0.1-0.20190806082151-9c14560f9ee8
 	github.com/muesli/coral v1.0.0
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
 	golang.org/x/image v0.7.0
 )
+
+require (
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/net v0.6.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+)
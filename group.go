@@ -0,0 +1,187 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"sync"
+)
+
+// GroupMember is a device which can be part of a DeckGroup. *Device
+// implements it.
+type GroupMember interface {
+	// Layout returns the number of rows and columns of keys.
+	Layout() (rows, columns uint8)
+	SetImage(index uint8, img image.Image) error
+	ReadKeys() (chan Key, error)
+	SetBrightness(percent uint8) error
+	Clear() error
+	Sleep() error
+	Wake() error
+	Close() error
+}
+
+// DeckGroup combines several devices, placed side by side from left to right,
+// into one logical surface. Keys get addressed by their row and column on the
+// whole surface, or by a global index counting row by row across all devices.
+type DeckGroup struct {
+	devices []GroupMember
+	rows    uint8
+	columns uint8
+}
+
+// GroupKey is a key event of a DeckGroup. Its Index is the global index of
+// the key on the surface.
+type GroupKey struct {
+	Key
+	Row    uint8
+	Col    uint8
+	Device GroupMember
+}
+
+// NewDeckGroup returns a DeckGroup of the given devices, which must be open,
+// ordered from left to right. Devices with fewer rows than others leave the
+// missing keys of the surface empty.
+func NewDeckGroup(devices ...GroupMember) *DeckGroup {
+	g := &DeckGroup{devices: devices}
+	for _, d := range devices {
+		rows, columns := d.Layout()
+		g.columns += columns
+		if rows > g.rows {
+			g.rows = rows
+		}
+	}
+	return g
+}
+
+// Rows returns the number of rows of the surface.
+func (g *DeckGroup) Rows() uint8 {
+	return g.rows
+}
+
+// Columns returns the number of columns of the surface.
+func (g *DeckGroup) Columns() uint8 {
+	return g.columns
+}
+
+// locate returns the device and the key index on it for a position on the
+// surface.
+func (g *DeckGroup) locate(row, col uint8) (GroupMember, uint8, error) {
+	c := col
+	for _, d := range g.devices {
+		rows, columns := d.Layout()
+		if c < columns {
+			if row >= rows {
+				break
+			}
+			return d, row*columns + c, nil
+		}
+		c -= columns
+	}
+	return nil, 0, fmt.Errorf("%w: key %d, %d, surface has %dx%d keys", ErrKeyOutOfRange, row, col, g.rows, g.columns)
+}
+
+// SetImageAt sets the image of the key at the given position on the surface.
+func (g *DeckGroup) SetImageAt(row, col uint8, img image.Image) error {
+	d, index, err := g.locate(row, col)
+	if err != nil {
+		return err
+	}
+	return d.SetImage(index, img)
+}
+
+// SetBrightness sets the brightness of all devices.
+func (g *DeckGroup) SetBrightness(percent uint8) error {
+	return g.each(func(d GroupMember) error {
+		return d.SetBrightness(percent)
+	})
+}
+
+// Clear clears all devices.
+func (g *DeckGroup) Clear() error {
+	return g.each(GroupMember.Clear)
+}
+
+// Sleep puts all devices to sleep.
+func (g *DeckGroup) Sleep() error {
+	return g.each(GroupMember.Sleep)
+}
+
+// Wake wakes all devices from sleep.
+func (g *DeckGroup) Wake() error {
+	return g.each(GroupMember.Wake)
+}
+
+// each calls fn for every device, even if it fails for some of them, and
+// returns the first error.
+func (g *DeckGroup) each(fn func(d GroupMember) error) error {
+	var first error
+	for _, d := range g.devices {
+		if err := fn(d); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// ReadKeys returns a channel, which it will use to emit the key presses and
+// releases of all devices. The channel gets closed once reading from all
+// devices has stopped.
+//
+// If reading from one of the devices fails, the devices which already started
+// reading get closed, as that's the only way to stop them. They need to be
+// opened again before calling ReadKeys once more.
+func (g *DeckGroup) ReadKeys() (chan GroupKey, error) {
+	channels := make([]chan Key, len(g.devices))
+	for i, d := range g.devices {
+		ch, err := d.ReadKeys()
+		if err != nil {
+			g.stopReading(channels[:i])
+			return nil, err
+		}
+		channels[i] = ch
+	}
+
+	out := make(chan GroupKey)
+	var wg sync.WaitGroup
+	var offset uint8
+	for i, d := range g.devices {
+		_, columns := d.Layout()
+		wg.Add(1)
+		go func(d GroupMember, keys chan Key, offset uint8) {
+			defer wg.Done()
+
+			for k := range keys {
+				row := k.Index / columns
+				col := offset + k.Index%columns
+				out <- GroupKey{
+					Key: Key{
+						Index:   row*g.columns + col,
+						Pressed: k.Pressed,
+					},
+					Row:    row,
+					Col:    col,
+					Device: d,
+				}
+			}
+		}(d, channels[i], offset)
+		offset += columns
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out, nil
+}
+
+// stopReading closes the devices whose key channels are given, draining the
+// channels so their read loops can stop.
+func (g *DeckGroup) stopReading(channels []chan Key) {
+	for i, ch := range channels {
+		go func(ch chan Key) {
+			for range ch {
+			}
+		}(ch)
+		_ = g.devices[i].Close()
+	}
+}
@@ -0,0 +1,203 @@
+package streamdeck
+
+import (
+	"errors"
+	"image"
+	"sync"
+	"testing"
+)
+
+// mockMember is a GroupMember recording what gets set on it.
+type mockMember struct {
+	rows, columns uint8
+	keys          chan Key
+	err           error
+	readErr       error
+
+	mutex      sync.Mutex
+	images     map[uint8]image.Image
+	brightness uint8
+	calls      []string
+}
+
+func newMockMember(rows, columns uint8) *mockMember {
+	return &mockMember{
+		rows:    rows,
+		columns: columns,
+		keys:    make(chan Key),
+		images:  make(map[uint8]image.Image),
+	}
+}
+
+func (m *mockMember) record(call string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.calls = append(m.calls, call)
+	return m.err
+}
+
+func (m *mockMember) Layout() (uint8, uint8) { return m.rows, m.columns }
+
+func (m *mockMember) SetImage(index uint8, img image.Image) error {
+	m.mutex.Lock()
+	m.images[index] = img
+	m.mutex.Unlock()
+	return m.record("SetImage")
+}
+
+func (m *mockMember) ReadKeys() (chan Key, error) {
+	if m.readErr != nil {
+		return nil, m.readErr
+	}
+	return m.keys, nil
+}
+
+func (m *mockMember) SetBrightness(percent uint8) error {
+	m.mutex.Lock()
+	m.brightness = percent
+	m.mutex.Unlock()
+	return m.record("SetBrightness")
+}
+
+func (m *mockMember) Clear() error { return m.record("Clear") }
+func (m *mockMember) Sleep() error { return m.record("Sleep") }
+func (m *mockMember) Wake() error  { return m.record("Wake") }
+
+// Close stops reading, like closing a device does.
+func (m *mockMember) Close() error {
+	close(m.keys)
+	return m.record("Close")
+}
+
+func TestDeckGroup(t *testing.T) {
+	left, right := newMockMember(3, 5), newMockMember(2, 4)
+	g := NewDeckGroup(left, right)
+	if g.Rows() != 3 || g.Columns() != 9 {
+		t.Fatalf("got a %dx%d surface, expected 3x9", g.Rows(), g.Columns())
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 72, 72))
+	if err := g.SetImageAt(2, 4, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetImageAt(1, 6, img); err != nil {
+		t.Fatal(err)
+	}
+	if left.images[14] != image.Image(img) || right.images[5] != image.Image(img) {
+		t.Errorf("got images on keys %v and %v, expected on 14 and 5", left.images, right.images)
+	}
+	// the right device has no third row
+	if err := g.SetImageAt(2, 6, img); !errors.Is(err, ErrKeyOutOfRange) {
+		t.Errorf("got %v for a missing key, expected ErrKeyOutOfRange", err)
+	}
+	if err := g.SetImageAt(0, 9, img); !errors.Is(err, ErrKeyOutOfRange) {
+		t.Errorf("got %v for a column past the surface, expected ErrKeyOutOfRange", err)
+	}
+
+	// broadcasts reach all devices, even if one of them fails
+	left.err = errors.New("unplugged")
+	if err := g.SetBrightness(40); err != left.err {
+		t.Errorf("got %v, expected the error of the left device", err)
+	}
+	left.err = nil
+	for _, fn := range []func() error{g.Clear, g.Sleep, g.Wake} {
+		if err := fn(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range []*mockMember{left, right} {
+		if m.brightness != 40 {
+			t.Errorf("got brightness %d, expected 40", m.brightness)
+		}
+		if got := m.calls[len(m.calls)-3:]; got[0] != "Clear" || got[1] != "Sleep" || got[2] != "Wake" {
+			t.Errorf("got calls %v, expected Clear, Sleep and Wake", got)
+		}
+	}
+}
+
+func TestDeckGroupReadKeys(t *testing.T) {
+	left, right := newMockMember(3, 5), newMockMember(2, 4)
+	g := NewDeckGroup(left, right)
+
+	keys, err := g.ReadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	right.keys <- Key{Index: 5, Pressed: true}
+	want := GroupKey{Key: Key{Index: 15, Pressed: true}, Row: 1, Col: 6, Device: right}
+	if k := <-keys; k != want {
+		t.Errorf("got %+v, expected %+v", k, want)
+	}
+	left.keys <- Key{Index: 7}
+	want = GroupKey{Key: Key{Index: 11}, Row: 1, Col: 2, Device: left}
+	if k := <-keys; k != want {
+		t.Errorf("got %+v, expected %+v", k, want)
+	}
+
+	// the channel gets closed once all devices stopped reading
+	close(left.keys)
+	close(right.keys)
+	if _, ok := <-keys; ok {
+		t.Error("got a key event after all devices stopped reading")
+	}
+}
+
+func TestDeckGroupReadKeysFails(t *testing.T) {
+	left, middle, right := newMockMember(3, 5), newMockMember(3, 5), newMockMember(3, 5)
+	right.readErr = errors.New("unplugged")
+	g := NewDeckGroup(left, middle, right)
+
+	if _, err := g.ReadKeys(); err != right.readErr {
+		t.Fatalf("got %v, expected the error of the right device", err)
+	}
+	// the devices which started reading got stopped
+	for _, m := range []*mockMember{left, middle} {
+		if len(m.calls) != 1 || m.calls[0] != "Close" {
+			t.Errorf("got calls %v, expected Close", m.calls)
+		}
+	}
+	if len(right.calls) != 0 {
+		t.Errorf("got calls %v on the failing device, expected none", right.calls)
+	}
+}
+
+func TestDeckGroupReadKeysReleasesDevices(t *testing.T) {
+	left, _ := newTestDevice(t)
+	right, _ := newTestDevice(t)
+	if _, err := right.ReadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewDeckGroup(left, right)
+	if _, err := g.ReadKeys(); err != ErrAlreadyReading {
+		t.Fatalf("got %v, expected ErrAlreadyReading", err)
+	}
+
+	// the left device got closed instead of being stuck reading for the group
+	waitFor(t, func() bool {
+		left.inputMutex.Lock()
+		defer left.inputMutex.Unlock()
+
+		return !left.reading
+	})
+	if _, err := left.ReadKeys(); err != ErrNotOpen {
+		t.Errorf("got %v, expected the left device to be closed", err)
+	}
+}
+
+func TestDeviceIsGroupMember(t *testing.T) {
+	d, f := newTestDevice(t)
+	g := NewDeckGroup(d)
+	if rows, columns := d.Layout(); g.Rows() != rows || g.Columns() != columns {
+		t.Errorf("got a %dx%d surface for a %dx%d device", g.Rows(), g.Columns(), rows, columns)
+	}
+
+	if err := g.SetImageAt(1, 2, d.NewKeyImage()); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.ImageWrites(7); n != 1 {
+		t.Errorf("got %d images on key 7, expected one", n)
+	}
+}
@@ -0,0 +1,115 @@
+package streamdeck
+
+import (
+	"errors"
+	"image/color"
+	"time"
+)
+
+const (
+	// Hold-to-confirm progress rings get redrawn 15 times a second.
+	holdFrameDelay = time.Second / 15
+)
+
+// holdConfirm is a hold-to-confirm action registered for a button.
+type holdConfirm struct {
+	duration  time.Duration
+	onConfirm func()
+	release   chan struct{}
+}
+
+// HoldToConfirm turns a button into a hold-to-confirm control: while the
+// button is held, a progress ring fills up on it, and onConfirm gets called
+// once it has been held for the given duration. Releasing the button early
+// cancels the action. Either way, the button's previous image gets restored.
+// Key events are still emitted by ReadKeys, which needs to be running. Passing
+// a nil onConfirm removes the control again.
+func (d *Device) HoldToConfirm(index uint8, duration time.Duration, onConfirm func()) error {
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	if onConfirm == nil {
+		if h, ok := d.holds[index]; ok && h.release != nil {
+			close(h.release)
+		}
+		delete(d.holds, index)
+		return nil
+	}
+	if duration <= 0 {
+		return errors.New("hold duration must be positive")
+	}
+
+	// a re-registered control cancels a hold that's still in progress
+	if h, ok := d.holds[index]; ok && h.release != nil {
+		close(h.release)
+	}
+	d.holds[index] = &holdConfirm{
+		duration:  duration,
+		onConfirm: onConfirm,
+	}
+	return nil
+}
+
+// trackHolds starts or cancels hold-to-confirm actions for the given key
+// events. The caller must hold the inputMutex.
+func (d *Device) trackHolds(keys []Key) {
+	for _, k := range keys {
+		h, ok := d.holds[k.Index]
+		if !ok {
+			continue
+		}
+
+		if k.Pressed && h.release == nil {
+			h.release = make(chan struct{})
+			go d.runHold(k.Index, h.duration, h.onConfirm, h.release)
+		}
+		if !k.Pressed && h.release != nil {
+			close(h.release)
+			h.release = nil
+		}
+	}
+}
+
+// runHold animates a hold-to-confirm progress ring until the button gets
+// released or the hold duration has passed.
+func (d *Device) runHold(index uint8, duration time.Duration, onConfirm func(), release chan struct{}) {
+	prev, ok := d.images.Get(index)
+	if !ok {
+		prev = newKeyImage(d.solidImage(color.RGBA{0, 0, 0, 255}))
+	}
+
+	done := d.startAnimation(index)
+	defer d.finishAnimation(index, done)
+
+	ticker := time.NewTicker(holdFrameDelay)
+	defer ticker.Stop()
+
+	size := int(d.KeyPixels(index))
+	start := time.Now()
+	for {
+		fraction := float64(time.Since(start)) / float64(duration)
+		if fraction >= 1 {
+			_, _ = d.setAnimationFrame(index, prev, done, false)
+			onConfirm()
+			return
+		}
+
+		frame := newKeyImage(renderProgress(size, fraction, ProgressOptions{Style: Radial}))
+		if ok, err := d.setAnimationFrame(index, frame, done, false); !ok || err != nil {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-release:
+			_, _ = d.setAnimationFrame(index, prev, done, false)
+			return
+		case <-done:
+			return
+		}
+	}
+}
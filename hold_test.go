@@ -0,0 +1,58 @@
+package streamdeck
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestHoldToConfirmReregister(t *testing.T) {
+	d, f := newTestDevice(t)
+	red := color.RGBA{255, 0, 0, 255}
+	if err := d.SetImage(0, d.solidImage(red)); err != nil {
+		t.Fatal(err)
+	}
+	confirmed := make(chan struct{}, 2)
+	onConfirm := func() { confirmed <- struct{}{} }
+	if err := d.HoldToConfirm(0, time.Hour, onConfirm); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := d.ReadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range keys {
+		}
+	}()
+	defer f.Disconnect(errors.New("unplugged"))
+
+	press := make([]byte, 4+15)
+	press[0] = 0x01
+	press[4] = 1
+	f.Report(press)
+	waitFor(t, func() bool {
+		return f.ImageWrites(0) > 1
+	})
+
+	// re-registering cancels the hold in progress and restores the image
+	if err := d.HoldToConfirm(0, time.Hour, onConfirm); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		img := f.LastImage(t, 0)
+		return near(img.At(36, 36), red)
+	})
+	n := f.ImageWrites(0)
+	time.Sleep(3 * holdFrameDelay)
+	if f.ImageWrites(0) != n {
+		t.Error("progress ring kept getting drawn after re-registering")
+	}
+	select {
+	case <-confirmed:
+		t.Error("cancelled hold got confirmed")
+	default:
+	}
+}
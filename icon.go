@@ -0,0 +1,91 @@
+package streamdeck
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// IconOptions customizes how SetIcon renders a glyph. Unset colors default to
+// a white glyph on a black background.
+type IconOptions struct {
+	Foreground color.Color
+	Background color.Color
+
+	// Font is the icon font containing the glyph, e.g. Font Awesome or
+	// Material Icons parsed with opentype.Parse. It is required.
+	Font *opentype.Font
+	// Size is the font size in pixels. It defaults to 60 percent of the key
+	// resolution.
+	Size float64
+}
+
+// SetIcon renders a single glyph of an icon font centered on a button.
+func (d *Device) SetIcon(index uint8, codepoint rune, opts IconOptions) error {
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+	img, err := renderIcon(int(d.KeyPixels(index)), codepoint, opts)
+	if err != nil {
+		return err
+	}
+	return d.SetImage(index, img)
+}
+
+// renderIcon returns a square image of the given size with the glyph centered
+// on it. The glyph's own bounds get centered, rather than its advance box, as
+// icon fonts rarely place their glyphs on the baseline.
+func renderIcon(size int, codepoint rune, opts IconOptions) (*image.RGBA, error) {
+	if opts.Font == nil {
+		return nil, errors.New("no icon font given")
+	}
+
+	fg := opts.Foreground
+	if fg == nil {
+		fg = color.White
+	}
+	bg := opts.Background
+	if bg == nil {
+		bg = color.Black
+	}
+
+	fontSize := opts.Size
+	if fontSize <= 0 {
+		fontSize = float64(size) * 0.6
+	}
+	f, err := textFace(opts.Font, fontSize)
+	if err != nil {
+		return nil, err
+	}
+	f.Lock()
+	defer f.Unlock()
+
+	bounds, _, ok := f.face.GlyphBounds(codepoint)
+	if !ok {
+		return nil, fmt.Errorf("font has no glyph for %U", codepoint)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	// GlyphBounds are relative to the dot, so move the dot such that the
+	// middle of the bounds lands on the middle of the image
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(fg),
+		Face: f.face,
+		Dot: fixed.Point26_6{
+			X: (fixed.I(size) - bounds.Min.X - bounds.Max.X) / 2,
+			Y: (fixed.I(size) - bounds.Min.Y - bounds.Max.Y) / 2,
+		},
+	}
+	drawer.DrawString(string(codepoint))
+
+	return img, nil
+}
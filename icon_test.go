@@ -0,0 +1,51 @@
+package streamdeck
+
+import (
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+func TestRenderIconCentered(t *testing.T) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// glyphs sitting on the baseline, below it, above it and on both sides
+	for _, r := range []rune{'T', 'g', '^', '.', 'j', '-'} {
+		for _, size := range []int{72, 96} {
+			img, err := renderIcon(size, r, IconOptions{Font: f})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			box := litBounds(img, func(r, g, b uint32) bool { return r > 0x4000 })
+			if box.Empty() {
+				t.Fatalf("%q at %dpx: no pixels got drawn", r, size)
+			}
+			// compare doubled coordinates to keep half pixels
+			center := box.Min.Add(box.Max)
+			for _, c := range []int{center.X, center.Y} {
+				if abs(c-size) > 2 {
+					t.Errorf("%q at %dpx: got glyph at %v, expected it centered", r, size, box)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderIconMissingGlyph(t *testing.T) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := renderIcon(72, '\U000F0000', IconOptions{Font: f}); err == nil {
+		t.Error("expected a missing glyph to fail")
+	}
+	if _, err := renderIcon(72, 'a', IconOptions{}); err == nil {
+		t.Error("expected rendering without a font to fail")
+	}
+}
@@ -0,0 +1,218 @@
+package streamdeck
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// SetImagesFunc calls fn for every button and sets the image it returns.
+// Buttons for which fn returns a nil image are left untouched. No image gets
+// set if fn returns an error. For example, to number the buttons:
+//
+//	d.SetImagesFunc(func(index uint8) (image.Image, error) {
+//		img := d.NewKeyImage()
+//		drawer := font.Drawer{
+//			Dst:  img,
+//			Src:  image.White,
+//			Face: basicfont.Face7x13,
+//			Dot:  fixed.P(int(d.Pixels)/2-3, int(d.Pixels)/2+4),
+//		}
+//		drawer.DrawString(strconv.Itoa(int(index)))
+//		return img, nil
+//	})
+func (d *Device) SetImagesFunc(fn func(index uint8) (image.Image, error)) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	images := make([]image.Image, d.Keys)
+	for i := range images {
+		img, err := fn(uint8(i))
+		if err != nil {
+			return err
+		}
+		images[i] = img
+	}
+
+	o := defaultImageOptions()
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	for i, img := range images {
+		if img == nil {
+			continue
+		}
+
+		d.stopAnimation(uint8(i))
+		if err := d.setImage(uint8(i), img, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetImagesGridFunc works like SetImagesFunc, but calls fn with the row and
+// column of every button, starting with 0, 0 for the top-left button.
+func (d *Device) SetImagesGridFunc(fn func(row, col uint8) (image.Image, error)) error {
+	return d.SetImagesFunc(func(index uint8) (image.Image, error) {
+		return fn(index/d.Columns, index%d.Columns)
+	})
+}
+
+// RenderToDeck spreads a single image across all buttons, treating it as the
+// device's whole faceplate, including the gaps between the buttons. The parts
+// of the image covered by the gaps are discarded, so artwork designed for the
+// faceplate lines up across buttons. Images of a different size get scaled to
+// the faceplate's resolution first.
+func (d *Device) RenderToDeck(img image.Image) error {
+	size := int(d.Pixels)
+	step := size + int(d.Padding)
+	width, height := d.PhysicalResolution()
+
+	face := image.NewRGBA(image.Rect(0, 0, width, height))
+	if img.Bounds().Size() == face.Bounds().Size() {
+		draw.Copy(face, image.Point{}, img, img.Bounds(), draw.Src, nil)
+	} else {
+		draw.CatmullRom.Scale(face, face.Bounds(), img, img.Bounds(), draw.Src, nil)
+	}
+
+	return d.SetImagesFunc(func(index uint8) (image.Image, error) {
+		col := int(index % d.Columns)
+		row := int(index / d.Columns)
+		src := image.Pt(col*step, row*step)
+
+		key := d.NewKeyImage()
+		draw.Copy(key, image.Point{}, face, image.Rectangle{Min: src, Max: src.Add(image.Pt(size, size))}, draw.Src, nil)
+		return key, nil
+	})
+}
+
+// SetImageRange sets the same image on the buttons from start to end,
+// inclusive. The image gets encoded only once, like FastClear does.
+func (d *Device) SetImageRange(start, end uint8, img image.Image) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if start > end {
+		return fmt.Errorf("invalid key range %d-%d", start, end)
+	}
+	if err := d.validateKey(end); err != nil {
+		return err
+	}
+
+	indexes := make([]uint8, 0, int(end-start)+1)
+	for i := int(start); i <= int(end); i++ {
+		indexes = append(indexes, uint8(i))
+	}
+	return d.setSameImage(indexes, img)
+}
+
+// SetImageRect sets the same image on all buttons in the rectangle spanning
+// from the top-left to the bottom-right row and column, inclusive.
+func (d *Device) SetImageRect(top, left, bottom, right uint8, img image.Image) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if top > bottom || left > right {
+		return fmt.Errorf("invalid key rectangle %d, %d - %d, %d", top, left, bottom, right)
+	}
+	if bottom >= d.Rows || right >= d.Columns {
+		return fmt.Errorf("%w: key %d, %d, device has %dx%d keys", ErrKeyOutOfRange, bottom, right, d.Rows, d.Columns)
+	}
+
+	var indexes []uint8
+	for row := top; row <= bottom; row++ {
+		for col := left; col <= right; col++ {
+			indexes = append(indexes, row*d.Columns+col)
+		}
+	}
+	return d.setSameImage(indexes, img)
+}
+
+// setSameImage encodes an image once and sets it on all given buttons. Every
+// button gets validated before anything is sent.
+func (d *Device) setSameImage(indexes []uint8, img image.Image) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+	for _, i := range indexes {
+		if err := d.validateKey(i); err != nil {
+			return err
+		}
+	}
+	opts := defaultImageOptions()
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	imageBytes, err := d.encodeImage(indexes[0], img, opts)
+	if err != nil {
+		return err
+	}
+
+	cached := newCachedImage(keyImage{img: img, opts: opts})
+	for _, i := range indexes {
+		d.stopAnimation(i)
+		// while blanked, only remember the image for waking up
+		if !d.asleep || d.asleepMode == DimOnly {
+			if err := d.writeImageData(i, imageBytes, opts); err != nil {
+				return err
+			}
+		}
+		d.images.SetCached(i, cached)
+	}
+
+	return nil
+}
+
+// SetImagesRaw sends images which are already encoded in the device's image
+// format, such as BMP or JPEG, to the given buttons, skipping all image
+// processing. The data must be ready to be sent as is, e.g. already flipped
+// into the device's orientation. Raw images can't be cached, so they aren't
+// restored after the buttons got blanked, and they are sent even while the
+// device is asleep.
+func (d *Device) SetImagesRaw(data map[uint8][]byte) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	maxSize := d.EncodedImageSize()
+	indexes := make([]int, 0, len(data))
+	for index, b := range data {
+		if err := d.validateKey(index); err != nil {
+			return err
+		}
+		if len(b) == 0 || len(b) > maxSize {
+			return fmt.Errorf("invalid image data for key %d: %d bytes, expected at most %d", index, len(b), maxSize)
+		}
+		// BMP images have a fixed size
+		if bytes.HasPrefix(b, []byte("BM")) && len(b) != maxSize {
+			return fmt.Errorf("invalid BMP image data for key %d: %d bytes, expected %d", index, len(b), maxSize)
+		}
+		indexes = append(indexes, int(index))
+	}
+	sort.Ints(indexes)
+
+	opts := defaultImageOptions()
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	for _, i := range indexes {
+		index := uint8(i)
+		d.stopAnimation(index)
+		if err := d.writeImageData(index, data[index], opts); err != nil {
+			return err
+		}
+		d.images.Delete(index)
+	}
+	return nil
+}
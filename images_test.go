@@ -0,0 +1,82 @@
+package streamdeck
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// keyColor returns a distinct color for every button.
+func keyColor(index uint8) color.RGBA {
+	return color.RGBA{R: 15 * index, G: 255 - 15*index, B: 0, A: 255}
+}
+
+// faceplateImage returns an image of the device's faceplate, filling every
+// button with its keyColor and the gaps between them with white.
+func faceplateImage(d *Device) *image.RGBA {
+	width, height := d.PhysicalResolution()
+	face := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(face, face.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	step := int(d.Pixels + d.Padding)
+	for i := uint8(0); i < d.Keys; i++ {
+		min := image.Pt(int(i%d.Columns)*step, int(i/d.Columns)*step)
+		r := image.Rectangle{Min: min, Max: min.Add(image.Pt(int(d.Pixels), int(d.Pixels)))}
+		draw.Draw(face, r, image.NewUniform(keyColor(i)), image.Point{}, draw.Src)
+	}
+	return face
+}
+
+func TestPhysicalResolution(t *testing.T) {
+	d, _ := newTestDevice(t)
+
+	// five columns and three rows of 72 pixels, with 16 pixels in between
+	if w, h := d.PhysicalResolution(); w != 424 || h != 248 {
+		t.Errorf("got a faceplate of %dx%d, expected 424x248", w, h)
+	}
+}
+
+func TestRenderToDeck(t *testing.T) {
+	d, f := newTestDevice(t)
+	face := faceplateImage(d)
+
+	// an image of half the resolution gets scaled up first
+	half := image.NewRGBA(image.Rect(0, 0, face.Bounds().Dx()/2, face.Bounds().Dy()/2))
+	draw.NearestNeighbor.Scale(half, half.Bounds(), face, face.Bounds(), draw.Src, nil)
+
+	for _, img := range []image.Image{face, half} {
+		f.Reset()
+		if err := d.RenderToDeck(img); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := uint8(0); i < d.Keys; i++ {
+			key := f.LastImage(t, i)
+			if key == nil {
+				t.Fatalf("key %d: got no image", i)
+			}
+			// none of the gaps may show up along the button's border
+			for _, p := range []image.Point{{36, 36}, {5, 5}, {66, 66}, {5, 66}, {66, 5}} {
+				if c := key.At(p.X, p.Y); !near(c, keyColor(i)) {
+					t.Errorf("%v image, key %d: got color %v at %v, expected %v",
+						img.Bounds().Size(), i, c, p, keyColor(i))
+				}
+			}
+		}
+	}
+}
+
+func TestSetSameImageValidatesEveryKey(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	err := d.setSameImage([]uint8{0, 1, d.Keys}, d.solidImage(color.White))
+	if !errors.Is(err, ErrKeyOutOfRange) {
+		t.Errorf("got error %v, expected ErrKeyOutOfRange", err)
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sent %d commands, expected none", len(ops))
+	}
+}
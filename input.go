@@ -0,0 +1,146 @@
+package streamdeck
+
+// SetEmergencyClear registers an action which gets triggered when all the
+// given keys are pressed at the same time, e.g. a hidden combination to
+// clear the device and exit a kiosk application. The action gets called from
+// the goroutine started by ReadKeys, once per press of the combination.
+// Passing no keys or a nil action disables it again.
+func (d *Device) SetEmergencyClear(indices []uint8, action func()) {
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	d.emergencyKeys = append([]uint8(nil), indices...)
+	d.emergencyAction = action
+	d.emergencyFired = false
+}
+
+// trackKeys updates the pressed state of the keys with the given events and
+// triggers the emergency action if its combination is pressed. If any key
+// changed, it returns a copy of the new state of all keys.
+func (d *Device) trackKeys(keys []Key) []bool {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	d.inputMutex.Lock()
+	for _, k := range keys {
+		if int(k.Index) < len(d.pressed) {
+			d.pressed[k.Index] = k.Pressed
+		}
+	}
+
+	d.trackHolds(keys)
+
+	var action func()
+	if d.emergencyAction != nil && len(d.emergencyKeys) > 0 {
+		combo := true
+		for _, i := range d.emergencyKeys {
+			if int(i) >= len(d.pressed) || !d.pressed[i] {
+				combo = false
+				break
+			}
+		}
+
+		if combo && !d.emergencyFired {
+			action = d.emergencyAction
+		}
+		d.emergencyFired = combo
+	}
+	state := append([]bool(nil), d.pressed...)
+	d.inputMutex.Unlock()
+
+	if action != nil {
+		action()
+	}
+	return state
+}
+
+// ReadKeyStates returns a channel, which it will use to emit the pressed state
+// of all keys whenever any of them changes. Stream Deck devices report the
+// state of all keys at once, so simultaneous presses arrive as a single state
+// change. Key states are read by the same loop as key events, so ReadKeys
+// needs to be called as well. Once requested, the channel must be drained
+// continuously, otherwise it blocks the delivery of key events as well.
+func (d *Device) ReadKeyStates() (chan []bool, error) {
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	if d.stateEvents == nil {
+		d.stateEvents = make(chan []bool)
+	}
+	return d.stateEvents, nil
+}
+
+// emitKeyStates sends the state of all keys to the channel returned by
+// ReadKeyStates.
+func (d *Device) emitKeyStates(state []bool) {
+	if state == nil {
+		return
+	}
+
+	d.inputMutex.Lock()
+	sch := d.stateEvents
+	d.inputMutex.Unlock()
+
+	if sch != nil {
+		sch <- state
+	}
+}
+
+// closeKeyStates closes the channel returned by ReadKeyStates.
+func (d *Device) closeKeyStates() {
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	if d.stateEvents != nil {
+		close(d.stateEvents)
+		d.stateEvents = nil
+	}
+}
+
+// OnDisconnect sets a function which gets called with the read error when
+// reading from the device fails, right before the channels returned by
+// ReadKeys and friends get closed. This allows telling a lost device apart
+// from a regular shutdown.
+func (d *Device) OnDisconnect(fn func(err error)) {
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	d.onDisconnect = fn
+}
+
+// disconnected notifies the disconnect handler, if any.
+func (d *Device) disconnected(err error) {
+	d.inputMutex.Lock()
+	fn := d.onDisconnect
+	d.inputMutex.Unlock()
+
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// SetKeyTraceHandler sets a function which gets called with every raw report
+// read from the device and the key events parsed from it, to help diagnose
+// input issues. It gets called from the goroutine started by ReadKeys. Passing
+// nil removes the handler.
+func (d *Device) SetKeyTraceHandler(fn func(raw []byte, parsed []Key)) {
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	d.keyTrace = fn
+}
+
+// traceKeys passes a report and its key events to the key trace handler, if
+// any.
+func (d *Device) traceKeys(report []byte, keys []Key) {
+	d.inputMutex.Lock()
+	fn := d.keyTrace
+	d.inputMutex.Unlock()
+
+	if fn != nil {
+		// the report buffer gets reused for the next read
+		raw := append([]byte(nil), report...)
+		fn(raw, append([]Key(nil), keys...))
+	}
+}
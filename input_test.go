@@ -0,0 +1,39 @@
+package streamdeck
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestOnDisconnect(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	var mutex sync.Mutex
+	var errs []error
+	d.OnDisconnect(func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		errs = append(errs, err)
+	})
+
+	keys, err := d.ReadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unplugged := errors.New("unplugged")
+	f.Disconnect(unplugged)
+
+	for range keys {
+	}
+
+	// the handler is done by the time the channel is closed
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(errs) != 1 {
+		t.Fatalf("disconnect handler got called %d times, expected once", len(errs))
+	}
+	if !errors.Is(errs[0], unplugged) {
+		t.Errorf("disconnect handler got %v, expected the read error", errs[0])
+	}
+}
@@ -0,0 +1,157 @@
+package streamdeck
+
+import (
+	"sync"
+	"time"
+)
+
+// Command names used in Metrics.
+const (
+	cmdFirmware   = "firmware"
+	cmdSerial     = "serial"
+	cmdReset      = "reset"
+	cmdBrightness = "brightness"
+	cmdImage      = "image"
+	cmdStandby    = "standby"
+	cmdProfile    = "profile"
+	cmdRaw        = "raw"
+)
+
+// Metrics holds statistics about the communication with a device.
+type Metrics struct {
+	// BytesWritten is the total number of bytes sent to the device.
+	BytesWritten uint64
+	// Commands is the total number of commands sent to the device.
+	Commands uint64
+	// Retries is the total number of retried commands.
+	Retries uint64
+	// Errors holds the number of failed commands per command type.
+	Errors map[string]uint64
+}
+
+// metrics keeps track of a device's Metrics.
+type metrics struct {
+	sync.Mutex
+	stats    Metrics
+	handler  func(Metrics)
+	observer func(cmd string, attempt uint, err error)
+
+	lastLatency time.Duration
+	avgLatency  time.Duration
+}
+
+// newMetrics returns an empty metrics tracker.
+func newMetrics() *metrics {
+	return &metrics{
+		stats: Metrics{
+			Errors: make(map[string]uint64),
+		},
+	}
+}
+
+// record a command that got sent to the device and notify the metrics
+// handler, if one is set.
+func (m *metrics) record(cmd string, bytesWritten int, err error) {
+	m.Lock()
+	m.stats.BytesWritten += uint64(bytesWritten)
+	m.stats.Commands++
+	if err != nil {
+		m.stats.Errors[cmd]++
+	}
+
+	handler := m.handler
+	var snapshot Metrics
+	if handler != nil {
+		snapshot = m.snapshot()
+	}
+	m.Unlock()
+
+	if handler != nil {
+		handler(snapshot)
+	}
+}
+
+// retry records a retried command and notifies the retry observer, if one is
+// set, with the error which caused the retry.
+func (m *metrics) retry(cmd string, attempt uint, err error) {
+	m.Lock()
+	m.stats.Retries++
+	observer := m.observer
+	m.Unlock()
+
+	if observer != nil {
+		observer(cmd, attempt, err)
+	}
+}
+
+// latency records how long a write to the device took. The average weighs
+// the latest write by a fifth, so it follows changes within a few writes.
+func (m *metrics) latency(t time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.lastLatency = t
+	if m.avgLatency == 0 {
+		m.avgLatency = t
+		return
+	}
+	m.avgLatency += (t - m.avgLatency) / 5
+}
+
+// snapshot returns a copy of the current stats. The caller must hold the lock.
+func (m *metrics) snapshot() Metrics {
+	s := m.stats
+	s.Errors = make(map[string]uint64, len(m.stats.Errors))
+	for k, v := range m.stats.Errors {
+		s.Errors[k] = v
+	}
+	return s
+}
+
+// SetMetricsHandler sets a function which gets called with the current
+// Metrics after every command sent to the device. Passing nil removes the
+// handler.
+func (d *Device) SetMetricsHandler(fn func(m Metrics)) {
+	d.metrics.Lock()
+	defer d.metrics.Unlock()
+
+	d.metrics.handler = fn
+}
+
+// SetRetryObserver sets a function which gets called whenever a command gets
+// retried, with the number of the retry, starting with 1, and the error which
+// caused it. Frequent retries usually point to a bad cable or hub. Passing nil
+// removes the observer.
+func (d *Device) SetRetryObserver(fn func(cmd string, attempt uint, err error)) {
+	d.metrics.Lock()
+	defer d.metrics.Unlock()
+
+	d.metrics.observer = fn
+}
+
+// Stats returns a snapshot of the device's current Metrics.
+func (d *Device) Stats() Metrics {
+	d.metrics.Lock()
+	defer d.metrics.Unlock()
+
+	return d.metrics.snapshot()
+}
+
+// LastWriteLatency returns how long the most recent write to the device took,
+// not counting the time it waited for other commands. Animations can use it to
+// adapt their frame rate to what the connection can handle.
+func (d *Device) LastWriteLatency() time.Duration {
+	d.metrics.Lock()
+	defer d.metrics.Unlock()
+
+	return d.metrics.lastLatency
+}
+
+// AverageWriteLatency returns a rolling average of how long writes to the
+// device take, weighing recent writes the most.
+func (d *Device) AverageWriteLatency() time.Duration {
+	d.metrics.Lock()
+	defer d.metrics.Unlock()
+
+	return d.metrics.avgLatency
+}
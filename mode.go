@@ -0,0 +1,81 @@
+package streamdeck
+
+import "time"
+
+const (
+	// Polling checks for new reports 100 times a second by default.
+	defaultPollInterval = 10 * time.Millisecond
+)
+
+// InputMode determines how ReadKeys waits for reports from the device.
+type InputMode int
+
+// Input modes.
+const (
+	// Blocking waits for reports with a blocking read. This has the lowest
+	// latency and doesn't use any CPU while waiting, but on some platforms
+	// the read can't be interrupted.
+	Blocking InputMode = iota
+	// Polling repeatedly reads with a timeout, checking in between whether
+	// the device got closed. This adds up to one poll interval of latency
+	// and wakes up the reading goroutine once per interval.
+	Polling
+)
+
+// timeoutReader is implemented by HID devices which can read with a timeout,
+// returning zero bytes if no report arrived in time.
+type timeoutReader interface {
+	ReadTimeout(b []byte, timeout time.Duration) (int, error)
+}
+
+// SetInputMode sets how ReadKeys waits for reports from the device, and how
+// often Polling checks for them. Polling requires a HIDDevice, passed to
+// NewDeviceWithHID, which implements ReadTimeout(b []byte, timeout
+// time.Duration) (int, error); the HID library used for regular devices only
+// offers blocking reads, so it returns ErrUnsupported otherwise. The mode
+// takes effect with the next report.
+func (d *Device) SetInputMode(mode InputMode, interval time.Duration) error {
+	if mode == Polling {
+		if _, ok := d.device.(timeoutReader); !ok {
+			return ErrUnsupported
+		}
+	}
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	d.inputMode = mode
+	d.pollInterval = interval
+	return nil
+}
+
+// readReport reads the next report from the HID device, according to the
+// input mode. While polling, it returns ErrClosed once the I/O queue got
+// closed. Both are passed in, as Close drops them from the device.
+func (d *Device) readReport(device HIDDevice, io *commandQueue, b []byte) error {
+	d.inputMutex.Lock()
+	mode, interval := d.inputMode, d.pollInterval
+	d.inputMutex.Unlock()
+
+	r, ok := device.(timeoutReader)
+	if mode != Polling || !ok {
+		_, err := device.Read(b)
+		return err
+	}
+
+	for {
+		n, err := r.ReadTimeout(b, interval)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return nil
+		}
+		if io.Closed() {
+			return ErrClosed
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"sync"
+)
+
+// keyNames maps names to button indexes.
+type keyNames struct {
+	sync.Mutex
+	indexes map[string]uint8
+}
+
+// NameKey assigns a name to a button, so it can be addressed by name, e.g. in
+// config-driven layouts. Naming another button with the same name moves the
+// name over. An empty name removes all names of the button.
+func (d *Device) NameKey(index uint8, name string) {
+	d.names.Lock()
+	defer d.names.Unlock()
+
+	if name == "" {
+		for n, i := range d.names.indexes {
+			if i == index {
+				delete(d.names.indexes, n)
+			}
+		}
+		return
+	}
+	d.names.indexes[name] = index
+}
+
+// IndexByName returns the index of the button with the given name and whether
+// there is one.
+func (d Device) IndexByName(name string) (uint8, bool) {
+	d.names.Lock()
+	defer d.names.Unlock()
+
+	index, ok := d.names.indexes[name]
+	return index, ok
+}
+
+// SetImageByName sets the image of the button with the given name. It returns
+// an error if no button has that name.
+func (d *Device) SetImageByName(name string, img image.Image) error {
+	index, ok := d.IndexByName(name)
+	if !ok {
+		return fmt.Errorf("unknown key name %q", name)
+	}
+	return d.SetImage(index, img)
+}
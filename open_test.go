@@ -0,0 +1,159 @@
+package streamdeck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+func TestMethodsBeforeOpen(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 72, 72))); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 72, 72))
+
+	tests := []struct {
+		name string
+		call func(d *Device) error
+		// methods which don't talk to the device work before Open
+		want error
+	}{
+		{"Close", (*Device).Close, ErrNotOpen},
+		{"FirmwareVersion", func(d *Device) error { _, err := d.FirmwareVersion(); return err }, ErrNotOpen},
+		{"ReadSerial", func(d *Device) error { _, err := d.ReadSerial(); return err }, ErrNotOpen},
+		{"Reset", func(d *Device) error { return d.Reset() }, ErrNotOpen},
+		{"Clear", (*Device).Clear, ErrNotOpen},
+		{"FastClear", (*Device).FastClear, ErrNotOpen},
+		{"ReadKeys", func(d *Device) error { _, err := d.ReadKeys(); return err }, ErrNotOpen},
+		{"Sleep", (*Device).Sleep, ErrNotOpen},
+		{"Wake", (*Device).Wake, ErrNotOpen},
+		{"Resume", func(d *Device) error { return d.Resume(map[uint8]image.Image{0: img}, 100) }, ErrNotOpen},
+		{"Fade", func(d *Device) error { return d.Fade(0, 100, time.Millisecond) }, ErrNotOpen},
+		{"SetBrightness", func(d *Device) error { return d.SetBrightness(50) }, ErrNotOpen},
+		{"IncreaseBrightness", func(d *Device) error { return d.IncreaseBrightness(10) }, ErrNotOpen},
+		{"DecreaseBrightness", func(d *Device) error { return d.DecreaseBrightness(10) }, ErrNotOpen},
+		{"SetImage", func(d *Device) error { return d.SetImage(0, img) }, ErrNotOpen},
+		{"SetImageOpt", func(d *Device) error { return d.SetImageOpt(0, img, WithFlip(false)) }, ErrNotOpen},
+		{"SetImageCropped", func(d *Device) error { return d.SetImageCropped(0, img) }, ErrNotOpen},
+		{"SetImageIfChanged", func(d *Device) error { _, err := d.SetImageIfChanged(0, img); return err }, ErrNotOpen},
+		{"SetImageOnBackground", func(d *Device) error { return d.SetImageOnBackground(0, img, color.White) }, ErrNotOpen},
+		{"SetImageAsync", func(d *Device) error { return <-d.SetImageAsync(0, img) }, ErrNotOpen},
+		{"SetImageTTL", func(d *Device) error { return d.SetImageTTL(0, img, time.Millisecond) }, ErrNotOpen},
+		{"SetImageRange", func(d *Device) error { return d.SetImageRange(0, 2, img) }, ErrNotOpen},
+		{"SetImageRect", func(d *Device) error { return d.SetImageRect(0, 0, 1, 1, img) }, ErrNotOpen},
+		{"SetImagesRaw", func(d *Device) error { return d.SetImagesRaw(map[uint8][]byte{0: {0xff}}) }, ErrNotOpen},
+		{"SetImagesFunc", func(d *Device) error {
+			return d.SetImagesFunc(func(uint8) (image.Image, error) { return img, nil })
+		}, ErrNotOpen},
+		{"SetImagesGridFunc", func(d *Device) error {
+			return d.SetImagesGridFunc(func(uint8, uint8) (image.Image, error) { return img, nil })
+		}, ErrNotOpen},
+		{"SetImageByName", func(d *Device) error { d.NameKey(0, "play"); return d.SetImageByName("play", img) }, ErrNotOpen},
+		{"SetImageFromReader", func(d *Device) error { return d.SetImageFromReader(0, bytes.NewReader(pngData.Bytes())) }, ErrNotOpen},
+		{"SetImageFromSVG", func(d *Device) error {
+			return d.SetImageFromSVG(0, []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"/>`))
+		}, ErrNotOpen},
+		{"SetImageFromURL", func(d *Device) error {
+			return d.SetImageFromURL(context.Background(), 0, "http://example.invalid/icon.png")
+		}, ErrNotOpen},
+		{"RenderToDeck", func(d *Device) error { return d.RenderToDeck(img) }, ErrNotOpen},
+		{"DrawKey", func(d *Device) error { return d.DrawKey(0, func(draw.Image) {}) }, ErrNotOpen},
+		{"SetKeyColorHex", func(d *Device) error { return d.SetKeyColorHex(0, "#f00") }, ErrNotOpen},
+		{"SetProgress", func(d *Device) error { return d.SetProgress(0, 0.5, ProgressOptions{}) }, ErrNotOpen},
+		{"SetText", func(d *Device) error { return d.SetText(0, "hi", TextOptions{}) }, ErrNotOpen},
+		{"SetAllText", func(d *Device) error { return d.SetAllText(map[uint8]string{0: "hi"}, TextOptions{}) }, ErrNotOpen},
+		{"SetAllTextGrid", func(d *Device) error {
+			return d.SetAllTextGrid(map[KeyPos]string{{Row: 0, Col: 0}: "hi"}, TextOptions{})
+		}, ErrNotOpen},
+		{"SetScrollingText", func(d *Device) error { _, err := d.SetScrollingText(0, "hi", ScrollOptions{}); return err }, ErrNotOpen},
+		{"Blink", func(d *Device) error { return d.Blink(0, color.White, 2, time.Millisecond) }, ErrNotOpen},
+		{"SetAnimation", func(d *Device) error {
+			return d.SetAnimation(0, []image.Image{img, img}, time.Millisecond, AnimationOptions{})
+		}, ErrNotOpen},
+		{"TransitionImage", func(d *Device) error { return d.TransitionImage(0, img, time.Millisecond, Crossfade) }, ErrNotOpen},
+		{"SetBadge", func(d *Device) error { return d.SetBadge(0, 3) }, ErrNotOpen},
+		{"RestoreState", func(d *Device) error { return d.RestoreState(DeckState{Brightness: 50}) }, ErrNotOpen},
+		{"GetFeatureReport", func(d *Device) error { _, err := d.GetFeatureReport(0x05, 32); return err }, ErrNotOpen},
+		{"SendFeatureReport", func(d *Device) error { return d.SendFeatureReport([]byte{0x03, 0x08, 50}) }, ErrNotOpen},
+
+		{"SetTouchStripImage", func(d *Device) error { return d.SetTouchStripImage(img) }, ErrUnsupported},
+		{"ReadEncoders", func(d *Device) error { _, err := d.ReadEncoders(); return err }, ErrUnsupported},
+		{"ReadTouch", func(d *Device) error { _, err := d.ReadTouch(); return err }, ErrUnsupported},
+		{"SetDeviceStandbyTimeout", func(d *Device) error { return d.SetDeviceStandbyTimeout(time.Minute) }, ErrUnsupported},
+		{"SwitchProfile", func(d *Device) error { return d.SwitchProfile(1) }, ErrUnsupported},
+		{"CurrentProfile", func(d *Device) error { _, err := d.CurrentProfile(); return err }, ErrUnsupported},
+		{"SetInputMode", func(d *Device) error { return d.SetInputMode(Polling, 0) }, ErrUnsupported},
+
+		{"ReadKeyStates", func(d *Device) error { _, err := d.ReadKeyStates(); return err }, nil},
+		{"SetEmergencyClear", func(d *Device) error { d.SetEmergencyClear([]uint8{0, 1}, func() {}); return nil }, nil},
+		{"OnDisconnect", func(d *Device) error { d.OnDisconnect(func(error) {}); return nil }, nil},
+		{"SetKeyTraceHandler", func(d *Device) error { d.SetKeyTraceHandler(func([]byte, []Key) {}); return nil }, nil},
+		{"HoldToConfirm", func(d *Device) error { return d.HoldToConfirm(0, time.Second, func() {}) }, nil},
+		{"SetBrightnessThrottled", func(d *Device) error { d.SetBrightnessThrottled(50); return nil }, nil},
+		{"SetBrightnessThrottleRate", func(d *Device) error { d.SetBrightnessThrottleRate(10); return nil }, nil},
+		{"SetBrightnessVerify", func(d *Device) error { d.SetBrightnessVerify(true); return nil }, nil},
+		{"OnBrightnessChange", func(d *Device) error { d.OnBrightnessChange(func(uint8, uint8) {}, true); return nil }, nil},
+		{"Brightness", func(d *Device) error { d.Brightness(); return nil }, nil},
+		{"NameKey", func(d *Device) error { d.NameKey(0, "play"); d.IndexByName("play"); return nil }, nil},
+		{"Thumbnail", func(d *Device) error { d.Thumbnail(0, 16); return nil }, nil},
+		{"Stats", func(d *Device) error { d.Stats(); return nil }, nil},
+		{"SetMetricsHandler", func(d *Device) error { d.SetMetricsHandler(func(Metrics) {}); return nil }, nil},
+		{"SetRetryObserver", func(d *Device) error { d.SetRetryObserver(func(string, uint, error) {}); return nil }, nil},
+		{"LastWriteLatency", func(d *Device) error { d.LastWriteLatency(); d.AverageWriteLatency(); return nil }, nil},
+		{"SetImageCacheSize", func(d *Device) error { d.SetImageCacheSize(4); return nil }, nil},
+		{"SetImageTransform", func(d *Device) error {
+			d.SetImageTransform(func(_ uint8, img image.Image) image.Image { return img })
+			return nil
+		}, nil},
+		{"SetKeyShape", func(d *Device) error { d.SetKeyShape(Circle); return nil }, nil},
+		{"SetSleepMode", func(d *Device) error { d.SetSleepMode(Blank); return nil }, nil},
+		{"SetSleepFadeDuration", func(d *Device) error { d.SetSleepFadeDuration(time.Second); return nil }, nil},
+		{"SetSleepTimeout", func(d *Device) error { d.SetSleepTimeout(time.Hour); d.SetSleepTimeout(0); return nil }, nil},
+		{"NotifyActivity", func(d *Device) error { d.NotifyActivity(); return nil }, nil},
+		{"PauseSleep", func(d *Device) error { d.PauseSleep(); d.ResumeSleep(); return nil }, nil},
+		{"Asleep", func(d *Device) error { d.Asleep(); return nil }, nil},
+		{"SetFadeDelay", func(d *Device) error { d.SetFadeDelay(time.Second); return nil }, nil},
+		{"SetRetryDelay", func(d *Device) error { d.SetRetryDelay(time.Second); return nil }, nil},
+		{"SetCommandTimeout", func(d *Device) error { d.SetCommandTimeout(cmdImage, time.Second); return nil }, nil},
+		{"SetClock", func(d *Device) error { d.SetClock(nil); return nil }, nil},
+		{"SetConfig", func(d *Device) error { d.SetConfig(Config{}); return nil }, nil},
+		{"SetDryRun", func(d *Device) error { d.SetDryRun(false); return nil }, nil},
+		{"SetHTTPClient", func(d *Device) error { d.SetHTTPClient(nil); return nil }, nil},
+		{"SaveState", func(d *Device) error { d.SaveState(); return nil }, nil},
+		{"SaveStateToFile", func(d *Device) error {
+			return d.SaveStateToFile(filepath.Join(t.TempDir(), "state.zip"))
+		}, nil},
+		{"ValidateImage", func(d *Device) error { return d.ValidateImage(img) }, nil},
+		{"IsValidKey", func(d *Device) error { d.IsValidKey(0); d.KeyPixels(0); return nil }, nil},
+		{"NewKeyImage", func(d *Device) error { d.NewKeyImage(); d.KeyBounds(); d.KeyColorModel(); return nil }, nil},
+		{"PhysicalResolution", func(d *Device) error { d.PhysicalResolution(); return nil }, nil},
+		{"Capabilities", func(d *Device) error { d.Capabilities(); return nil }, nil},
+		{"EncodedImageSize", func(d *Device) error { d.EncodedImageSize(); d.TransferChunkSize(); return nil }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeHIDDevice()
+			d, err := NewDeviceWithHID(testDeviceInfo(PID_STREAMDECK_MK2), f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.call(d); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, expected %v", err, tt.want)
+			}
+			if ops := f.Ops(); len(ops) > 0 {
+				t.Errorf("sent %d commands to a device which isn't open", len(ops))
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package streamdeck
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+)
+
+// Fit determines how images which don't match the device's key resolution
+// get scaled.
+type Fit int
+
+// Fit modes.
+const (
+	// Exact requires images to match the key resolution.
+	Exact Fit = iota
+	// Stretch scales images to the key resolution, ignoring their aspect ratio.
+	Stretch
+	// Contain scales images to fit on the key, keeping their aspect ratio and
+	// filling the remaining area with black.
+	Contain
+	// Cover scales images to fill the whole key, keeping their aspect ratio and
+	// cropping whatever doesn't fit.
+	Cover
+)
+
+// ImageOption customizes how SetImageOpt processes and sends an image.
+type ImageOption func(*imageOptions)
+
+// imageOptions holds the settings used to send an image to a button.
+type imageOptions struct {
+	fit     Fit
+	anchor  Anchor
+	flip    bool
+	quality int
+	force   bool
+	ctx     context.Context
+	badge   int
+
+	shape      KeyShape
+	shapeSet   bool
+	background color.RGBA
+}
+
+// defaultImageOptions returns the settings used by SetImage.
+func defaultImageOptions() imageOptions {
+	return imageOptions{
+		fit:     Exact,
+		flip:    true,
+		quality: 100,
+	}
+}
+
+// WithFit sets how images get scaled to the key resolution. It defaults to
+// Exact.
+func WithFit(fit Fit) ImageOption {
+	return func(o *imageOptions) {
+		o.fit = fit
+	}
+}
+
+// Anchor determines which part of an image is kept when Cover crops it.
+type Anchor int
+
+// Crop anchors.
+const (
+	// AnchorCenter keeps the middle of the image.
+	AnchorCenter Anchor = iota
+	// AnchorTop keeps the top of portrait images.
+	AnchorTop
+	// AnchorBottom keeps the bottom of portrait images.
+	AnchorBottom
+	// AnchorLeft keeps the left of landscape images.
+	AnchorLeft
+	// AnchorRight keeps the right of landscape images.
+	AnchorRight
+)
+
+// WithAnchor sets which part of an image is kept when it gets cropped to fit
+// the key. It defaults to AnchorCenter.
+func WithAnchor(anchor Anchor) ImageOption {
+	return func(o *imageOptions) {
+		o.anchor = anchor
+	}
+}
+
+// WithFlip controls whether the image gets flipped or rotated into the
+// orientation the device expects. It defaults to true. Disable it for images
+// which have already been transformed by the caller, to save a copy of the
+// image.
+func WithFlip(flip bool) ImageOption {
+	return func(o *imageOptions) {
+		o.flip = flip
+	}
+}
+
+// WithQuality sets the encoding quality from 1 to 100, for devices using a
+// lossy image format. It defaults to 100.
+func WithQuality(quality int) ImageOption {
+	return func(o *imageOptions) {
+		if quality < 1 {
+			quality = 1
+		}
+		if quality > 100 {
+			quality = 100
+		}
+		o.quality = quality
+	}
+}
+
+// WithForceWrite always sends the image to the device, even when it would
+// otherwise only be cached, e.g. while the device's images are blanked during
+// sleep.
+func WithForceWrite() ImageOption {
+	return func(o *imageOptions) {
+		o.force = true
+	}
+}
+
+// WithContext aborts sending the image when the context is done.
+func WithContext(ctx context.Context) ImageOption {
+	return func(o *imageOptions) {
+		o.ctx = ctx
+	}
+}
+
+// SetImageTransform sets a function which gets applied to every image right
+// before it's sent to a button, e.g. to tint all images or to add a
+// watermark. Cached images are kept untransformed. The function must return
+// an image of the same size, otherwise sending the image fails with a
+// *DimensionError. Passing nil removes the transform.
+func (d *Device) SetImageTransform(fn func(index uint8, img image.Image) image.Image) {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	d.transform = fn
+}
+
+// SetImageOpt sets the image of a button on the Stream Deck, just like
+// SetImage, customized by the given options.
+func (d *Device) SetImageOpt(index uint8, img image.Image, opts ...ImageOption) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	o := defaultImageOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	img, err := fitImage(img, o.fit, o.anchor, int(d.KeyPixels(index)))
+	if err != nil {
+		return err
+	}
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	d.stopAnimation(index)
+	return d.setImage(index, img, o)
+}
+
+// fitImage scales the image to a square of the given size, using the given
+// fit mode. Empty images can't be scaled and result in a *DimensionError.
+func fitImage(img image.Image, fit Fit, anchor Anchor, size int) (image.Image, error) {
+	b := img.Bounds()
+	if fit == Exact || (b.Dx() == size && b.Dy() == size) {
+		return img, nil
+	}
+	if b.Empty() {
+		return nil, &DimensionError{Width: b.Dx(), Height: b.Dy(), Expected: uint(size)}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	target := dst.Bounds()
+	src := b
+
+	switch fit {
+	case Contain:
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.RGBA{0, 0, 0, 255}), image.Point{}, draw.Src)
+		if b.Dx() > b.Dy() {
+			h := size * b.Dy() / b.Dx()
+			target = image.Rect(0, (size-h)/2, size, (size-h)/2+h)
+		} else {
+			w := size * b.Dx() / b.Dy()
+			target = image.Rect((size-w)/2, 0, (size-w)/2+w, size)
+		}
+	case Cover:
+		if b.Dx() > b.Dy() {
+			x := b.Min.X + cropOffset(b.Dx()-b.Dy(), anchor == AnchorLeft, anchor == AnchorRight)
+			src = image.Rect(x, b.Min.Y, x+b.Dy(), b.Max.Y)
+		} else {
+			y := b.Min.Y + cropOffset(b.Dy()-b.Dx(), anchor == AnchorTop, anchor == AnchorBottom)
+			src = image.Rect(b.Min.X, y, b.Max.X, y+b.Dx())
+		}
+	}
+
+	draw.CatmullRom.Scale(dst, target, img, src, draw.Src, nil)
+	return dst, nil
+}
+
+// cropOffset returns where to start cropping along an axis, given by how much
+// the image needs to be cropped and whether to keep its start or its end.
+// Otherwise it gets cropped evenly on both sides.
+func cropOffset(excess int, start, end bool) int {
+	switch {
+	case start:
+		return 0
+	case end:
+		return excess
+	}
+	return excess / 2
+}
+
+// SetImageCropped crops an image to a square and scales it to the key
+// resolution before setting it on a button, which suits photos better than
+// stretching them. The crop is centered, unless WithAnchor is passed.
+func (d *Device) SetImageCropped(index uint8, img image.Image, opts ...ImageOption) error {
+	return d.SetImageOpt(index, img, append([]ImageOption{WithFit(Cover)}, opts...)...)
+}
+
+// SetImageIfChanged sets the image of a button like SetImage, unless the
+// button already shows the same image. It returns whether the image got
+// written.
+func (d *Device) SetImageIfChanged(index uint8, img image.Image) (bool, error) {
+	if !d.opened {
+		return false, ErrNotOpen
+	}
+
+	if err := d.validateKeyImage(index, img); err != nil {
+		return false, err
+	}
+	o := defaultImageOptions()
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	// a stopped animation may have left one of its frames on the button
+	animated := d.stopAnimation(index)
+	if cached, ok := d.images.Get(index); ok && !animated && cached.opts == o && imagesEqual(cached.img, img) {
+		return false, nil
+	}
+	return true, d.setImage(index, img, o)
+}
@@ -0,0 +1,181 @@
+package streamdeck
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// halvesImage returns an image with a red left and a blue right half.
+func halvesImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, image.Rect(0, 0, w/2, h), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(w/2, 0, w, h), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestSetImageOpt(t *testing.T) {
+	red, blue, black := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}, color.RGBA{0, 0, 0, 255}
+
+	tt := []struct {
+		name string
+		img  image.Image
+		opts []ImageOption
+		// the expected colors on the device, which sees the image rotated
+		// by 180 degrees unless flipping got disabled
+		at map[image.Point]color.Color
+	}{
+		{"exact", halvesImage(72, 72), nil,
+			map[image.Point]color.Color{{10, 36}: blue, {60, 36}: red}},
+		{"exact without flip", halvesImage(72, 72), []ImageOption{WithFlip(false)},
+			map[image.Point]color.Color{{10, 36}: red, {60, 36}: blue}},
+		{"stretch", halvesImage(20, 10), []ImageOption{WithFit(Stretch)},
+			map[image.Point]color.Color{{10, 10}: blue, {60, 60}: red}},
+		{"contain", halvesImage(144, 72), []ImageOption{WithFit(Contain)},
+			map[image.Point]color.Color{{36, 5}: black, {10, 36}: blue, {60, 36}: red, {36, 66}: black}},
+		{"contain without flip", halvesImage(144, 72), []ImageOption{WithFit(Contain), WithFlip(false)},
+			map[image.Point]color.Color{{36, 5}: black, {10, 36}: red, {60, 36}: blue}},
+		{"cover", halvesImage(144, 72), []ImageOption{WithFit(Cover), WithQuality(90)},
+			map[image.Point]color.Color{{10, 36}: blue, {60, 36}: red}},
+		{"cover anchored left", halvesImage(144, 72), []ImageOption{WithFit(Cover), WithAnchor(AnchorLeft), WithFlip(false)},
+			map[image.Point]color.Color{{10, 36}: red, {60, 36}: red}},
+		{"cover anchored right", halvesImage(144, 72), []ImageOption{WithFit(Cover), WithAnchor(AnchorRight)},
+			map[image.Point]color.Color{{10, 36}: blue, {60, 36}: blue}},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			d, f := newTestDevice(t)
+			if err := d.SetImageOpt(0, test.img, test.opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			img := f.LastImage(t, 0)
+			for p, want := range test.at {
+				if c := img.At(p.X, p.Y); !near(c, want) {
+					t.Errorf("got color %v at %v, expected %v", c, p, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetImageOptDimensions(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	var dimErr *DimensionError
+	if err := d.SetImageOpt(0, halvesImage(20, 10)); !errors.As(err, &dimErr) {
+		t.Errorf("exact fit accepted a 20x10 image: %v", err)
+	}
+	for _, fit := range []Fit{Stretch, Contain, Cover} {
+		if err := d.SetImageOpt(0, image.NewRGBA(image.Rectangle{}), WithFit(fit)); !errors.As(err, &dimErr) {
+			t.Errorf("fit mode %d: got %v for an empty image, expected a *DimensionError", fit, err)
+		}
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sent %d commands, expected none", len(ops))
+	}
+}
+
+func TestWithQuality(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	// noise compresses badly, so the quality makes a difference
+	img := image.NewRGBA(image.Rect(0, 0, 72, 72))
+	rnd := rand.New(rand.NewSource(1))
+	rnd.Read(img.Pix)
+
+	var sizes []int
+	for _, q := range []int{100, 50, 1} {
+		f.Reset()
+		if err := d.SetImageOpt(0, img, WithQuality(q)); err != nil {
+			t.Fatal(err)
+		}
+		images := f.Images()
+		if len(images) != 1 {
+			t.Fatalf("quality %d: got %d images, expected one", q, len(images))
+		}
+		sizes = append(sizes, len(images[0].data))
+	}
+	if !(sizes[0] > sizes[1] && sizes[1] > sizes[2]) {
+		t.Errorf("got image sizes %v for qualities 100, 50 and 1, expected them to shrink", sizes)
+	}
+}
+
+func TestWithForceWrite(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetSleepMode(Blank)
+	d.SetSleepFadeDuration(0)
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Reset()
+	img := halvesImage(144, 72)
+	if err := d.SetImageOpt(0, img, WithFit(Contain)); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.Images()); n != 0 {
+		t.Errorf("got %d images while asleep, expected them to be cached only", n)
+	}
+	if err := d.SetImageOpt(0, img, WithFit(Contain), WithForceWrite()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.Images()); n != 1 {
+		t.Errorf("got %d images while asleep, expected the forced one", n)
+	}
+}
+
+func TestSetImageIfChangedAliased(t *testing.T) {
+	d, f := newTestDevice(t)
+	img := d.solidImage(color.RGBA{255, 0, 0, 255})
+	if err := d.SetImage(0, img); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := d.SetImageIfChanged(0, img); err != nil || written {
+		t.Fatalf("unchanged image: got written %v, %v", written, err)
+	}
+
+	// the same image, changed in place, still needs to be written
+	blue := color.RGBA{0, 0, 255, 255}
+	draw.Draw(img, img.Bounds(), image.NewUniform(blue), image.Point{}, draw.Src)
+	written, err := d.SetImageIfChanged(0, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !written {
+		t.Fatal("image changed in place didn't get written")
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, blue) {
+		t.Errorf("got color %v, expected blue", c)
+	}
+}
+
+func TestSetImageIfChanged(t *testing.T) {
+	d, f := newTestDevice(t)
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+
+	if written, err := d.SetImageIfChanged(0, d.solidImage(red)); err != nil || !written {
+		t.Fatalf("first image: got written %v, %v", written, err)
+	}
+	// a different image with the same pixels doesn't get written again
+	for i := 0; i < 3; i++ {
+		if written, err := d.SetImageIfChanged(0, d.solidImage(red)); err != nil || written {
+			t.Fatalf("identical image: got written %v, %v", written, err)
+		}
+	}
+	if n := f.ImageWrites(0); n != 1 {
+		t.Errorf("got %d image writes, expected one", n)
+	}
+
+	if written, err := d.SetImageIfChanged(0, d.solidImage(blue)); err != nil || !written {
+		t.Fatalf("changed image: got written %v, %v", written, err)
+	}
+	if n := f.ImageWrites(0); n != 2 {
+		t.Errorf("got %d image writes, expected two", n)
+	}
+}
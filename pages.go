@@ -0,0 +1,111 @@
+package streamdeck
+
+import (
+	"errors"
+	"image"
+	"sync"
+)
+
+// ErrNoPage is returned when popping a page off an empty PageStack.
+var ErrNoPage = errors.New("no page to pop")
+
+// PageStack implements push/pop navigation between pages of button images,
+// e.g. for folders or sub-menus.
+type PageStack struct {
+	d *Device
+
+	mutex sync.Mutex
+	pages []savedPage
+}
+
+// savedPage is a page pushed down the stack. It keeps the images encoded like
+// the device's image cache does, so deep stacks don't hold on to the decoded
+// pixels of every page.
+type savedPage struct {
+	brightness uint8
+	images     map[uint8]cachedImage
+}
+
+// savePage returns the page currently shown on the device.
+func savePage(d *Device) savedPage {
+	page := savedPage{
+		brightness: d.savedBrightness(),
+		images:     make(map[uint8]cachedImage),
+	}
+	for i := uint8(0); i < d.Keys; i++ {
+		if ci, ok := d.images.PeekCached(i); ok {
+			page.images[i] = ci
+		}
+	}
+	return page
+}
+
+// state decodes the page into a DeckState.
+func (p savedPage) state() DeckState {
+	s := DeckState{
+		Brightness: p.brightness,
+		Images:     make(map[uint8]image.Image),
+		opts:       make(map[uint8]imageOptions),
+	}
+	for i, ci := range p.images {
+		ki := ci.keyImage()
+		s.Images[i] = ki.img
+		s.opts[i] = ki.opts
+	}
+	return s
+}
+
+// NewPageStack returns an empty PageStack for the device.
+func NewPageStack(d *Device) *PageStack {
+	return &PageStack{
+		d: d,
+	}
+}
+
+// PushPage saves the current page and shows the given images instead. Buttons
+// without an image on the new page are cleared. Only buttons whose image
+// changes get written.
+func (p *PageStack) PushPage(images map[uint8]image.Image) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	current := savePage(p.d)
+	page := DeckState{
+		Brightness: current.brightness,
+		Images:     images,
+	}
+	if err := p.d.RestoreState(page); err != nil {
+		return err
+	}
+
+	p.pages = append(p.pages, current)
+	return nil
+}
+
+// PopPage restores the page which was shown before the last PushPage.
+func (p *PageStack) PopPage() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.pages) == 0 {
+		return ErrNoPage
+	}
+
+	page := p.pages[len(p.pages)-1].state()
+	page.Brightness = p.d.savedBrightness()
+	if err := p.d.RestoreState(page); err != nil {
+		return err
+	}
+
+	p.pages = p.pages[:len(p.pages)-1]
+	return nil
+}
+
+// Depth returns the number of pages pushed onto the stack. Applications can
+// use it to route key events to the handlers of the active page.
+func (p *PageStack) Depth() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.pages)
+}
@@ -0,0 +1,41 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPageStack(t *testing.T) {
+	d, f := newTestDevice(t)
+	red, green := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}
+	if err := d.SetImage(0, d.solidImage(red)); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPageStack(d)
+	if err := p.PushPage(map[uint8]image.Image{1: d.solidImage(green)}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Depth() != 1 {
+		t.Errorf("got depth %d, expected 1", p.Depth())
+	}
+	if ci := p.pages[0].images[0]; len(ci.data) == 0 {
+		t.Error("pushed page doesn't keep its images encoded")
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, color.Black) {
+		t.Errorf("key 0: got color %v on the new page, expected it cleared", c)
+	}
+
+	if err := p.PopPage(); err != nil {
+		t.Fatal(err)
+	}
+	for index, want := range map[uint8]color.RGBA{0: red, 1: {0, 0, 0, 255}} {
+		if c := f.LastImage(t, index).At(36, 36); !near(c, want) {
+			t.Errorf("key %d: got color %v after popping the page, expected %v", index, c, want)
+		}
+	}
+	if err := p.PopPage(); err != ErrNoPage {
+		t.Errorf("popping an empty stack returned %v, expected ErrNoPage", err)
+	}
+}
@@ -0,0 +1,161 @@
+package streamdeck
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// PersistentDevice keeps a Stream Deck configured across disconnects. It
+// remembers the brightness and button images set through it and, whenever the
+// device needs to be (re)opened, applies them again. Errors talking to the
+// device only cause a reconnect on the next call; only errors which a
+// reconnect can't fix, such as images of the wrong size, are returned.
+type PersistentDevice struct {
+	mu     sync.Mutex
+	serial string
+	dev    *Device
+
+	brightness    uint8
+	hasBrightness bool
+	images        map[uint8]image.Image
+}
+
+// NewPersistentDevice returns a PersistentDevice for the Stream Deck with the
+// given serial number, or the first one found if serial is empty. It doesn't
+// connect to the device until it's first used.
+func NewPersistentDevice(serial string) *PersistentDevice {
+	return &PersistentDevice{
+		serial: serial,
+		images: make(map[uint8]image.Image),
+	}
+}
+
+// SetImage sets the image of a button, remembering it for reconnects. If the
+// device is currently unavailable, the image gets set once it's back.
+func (p *PersistentDevice) SetImage(index uint8, img image.Image) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.connect() {
+		p.images[index] = img
+		return nil
+	}
+
+	if err := p.dev.validateKeyImage(index, img); err != nil {
+		return err
+	}
+	p.images[index] = img
+	p.check(p.dev.SetImage(index, img))
+	return nil
+}
+
+// SetBrightness sets the brightness, remembering it for reconnects. If the
+// device is currently unavailable, the brightness gets set once it's back.
+func (p *PersistentDevice) SetBrightness(percent uint8) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.brightness = percent
+	p.hasBrightness = true
+
+	if p.dev == nil {
+		// connecting applies the brightness
+		p.connect()
+		return nil
+	}
+	p.check(p.dev.SetBrightness(percent))
+	return nil
+}
+
+// Connected returns true if the device is currently connected, trying to
+// connect to it otherwise.
+func (p *PersistentDevice) Connected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.connect()
+}
+
+// Close closes the connection with the device. The PersistentDevice
+// reconnects when it's used again.
+func (p *PersistentDevice) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dev == nil {
+		return nil
+	}
+	err := p.dev.Close()
+	p.dev = nil
+	return err
+}
+
+// connect opens the device and applies the remembered state, unless it's
+// already connected. It returns false if the device is unavailable. The caller
+// must hold the lock.
+func (p *PersistentDevice) connect() bool {
+	if p.dev != nil {
+		return true
+	}
+
+	dev, err := p.find()
+	if err != nil {
+		return false
+	}
+	if err := dev.Open(); err != nil {
+		return false
+	}
+	p.dev = dev
+
+	if p.hasBrightness {
+		if !p.check(dev.SetBrightness(p.brightness)) {
+			return false
+		}
+	}
+	for index, img := range p.images {
+		err := dev.SetImage(index, img)
+
+		var dimErr *DimensionError
+		if errors.As(err, &dimErr) || errors.Is(err, ErrKeyOutOfRange) {
+			// reconnected to a different model, drop what doesn't fit
+			delete(p.images, index)
+			continue
+		}
+		if !p.check(err) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// find returns the device with the serial number, or the first one if no
+// serial number is set.
+func (p *PersistentDevice) find() (*Device, error) {
+	devs, err := Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev := range devs {
+		if p.serial == "" || dev.Serial == p.serial {
+			dev := dev
+			return &dev, nil
+		}
+	}
+	return nil, fmt.Errorf("no Stream Deck with serial %q found", p.serial)
+}
+
+// check drops the connection if err is not nil, so the next call reconnects.
+// It returns false in that case. The caller must hold the lock.
+func (p *PersistentDevice) check(err error) bool {
+	if err == nil {
+		return true
+	}
+
+	_ = p.dev.Close()
+	p.dev = nil
+	return false
+}
@@ -0,0 +1,36 @@
+package streamdeck
+
+import "errors"
+
+// SwitchProfile makes the firmware switch to one of the profiles stored on the
+// device itself. None of the supported models are known to store profiles, so
+// it currently returns ErrUnsupported.
+func (d *Device) SwitchProfile(slot uint8) error {
+	if !d.Capabilities().Has(CapProfiles) {
+		return ErrUnsupported
+	}
+
+	report := make([]byte, len(d.setProfileCommand)+1)
+	copy(report, d.setProfileCommand)
+	report[len(report)-1] = slot
+
+	return d.sendFeatureReport(cmdProfile, report)
+}
+
+// CurrentProfile returns the profile the firmware currently uses. None of the
+// supported models are known to store profiles, so it currently returns
+// ErrUnsupported.
+func (d Device) CurrentProfile() (uint8, error) {
+	if !d.Capabilities().Has(CapProfiles) {
+		return 0, ErrUnsupported
+	}
+
+	result, err := d.getFeatureReport(cmdProfile, d.getProfileCommand)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) <= len(d.getProfileCommand) {
+		return 0, errors.New("profile report too short")
+	}
+	return result[len(d.getProfileCommand)], nil
+}
@@ -0,0 +1,61 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// filledFraction returns which fraction of the progress indicator's pixels
+// are drawn in the foreground color rather than the track color.
+func filledFraction(img *image.RGBA) float64 {
+	var filled, track int
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			switch img.RGBAAt(x, y) {
+			case color.RGBA{255, 255, 255, 255}:
+				filled++
+			case color.RGBA{64, 64, 64, 255}:
+				track++
+			}
+		}
+	}
+	if filled+track == 0 {
+		return math.NaN()
+	}
+	return float64(filled) / float64(filled+track)
+}
+
+func TestRenderProgress(t *testing.T) {
+	for _, style := range []ProgressStyle{HorizontalBar, Radial} {
+		for _, fraction := range []float64{0, 0.5, 1} {
+			img := renderProgress(72, fraction, ProgressOptions{Style: style})
+			if b := img.Bounds(); b != image.Rect(0, 0, 72, 72) {
+				t.Fatalf("style %d: got bounds %v, expected 72x72", style, b)
+			}
+			if got := filledFraction(img); math.Abs(got-fraction) > 0.02 {
+				t.Errorf("style %d: got %.3f filled for fraction %v", style, got, fraction)
+			}
+		}
+	}
+}
+
+func TestRenderProgressClamps(t *testing.T) {
+	for _, style := range []ProgressStyle{HorizontalBar, Radial} {
+		empty := renderProgress(72, 0, ProgressOptions{Style: style})
+		full := renderProgress(72, 1, ProgressOptions{Style: style})
+
+		for _, fraction := range []float64{-0.5, math.Inf(-1), math.NaN()} {
+			if !imagesEqual(renderProgress(72, fraction, ProgressOptions{Style: style}), empty) {
+				t.Errorf("style %d: fraction %v isn't drawn like 0", style, fraction)
+			}
+		}
+		for _, fraction := range []float64{1.5, math.Inf(1)} {
+			if !imagesEqual(renderProgress(72, fraction, ProgressOptions{Style: style}), full) {
+				t.Errorf("style %d: fraction %v isn't drawn like 1", style, fraction)
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package streamdeck
+
+import (
+	"image"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of commands which can be queued before enqueueing blocks.
+	commandQueueSize = 64
+)
+
+// commandQueue executes commands one after another on a single goroutine, in
+// the order they got enqueued. Every device uses one queue for its
+// asynchronous commands and another one for all writes to the HID device, so
+// asynchronous commands can perform I/O without waiting for themselves. Reads
+// don't go through a queue, as they block until the device sends a report.
+type commandQueue struct {
+	mutex  sync.Mutex
+	cmds   chan func()
+	closed bool
+	done   chan struct{}
+}
+
+// newCommandQueue returns a queue and starts executing its commands.
+func newCommandQueue() *commandQueue {
+	q := &commandQueue{
+		cmds: make(chan func(), commandQueueSize),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(q.done)
+		for cmd := range q.cmds {
+			cmd()
+		}
+	}()
+
+	return q
+}
+
+// Enqueue adds a command to the queue. It returns ErrClosed if the queue has
+// been closed.
+func (q *commandQueue) Enqueue(cmd func()) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	q.cmds <- cmd
+	return nil
+}
+
+// Do executes a command and waits for it to finish, returning its error.
+func (q *commandQueue) Do(cmd func() error) error {
+	result := make(chan error, 1)
+	if err := q.Enqueue(func() {
+		result <- cmd()
+	}); err != nil {
+		return err
+	}
+	return <-result
+}
+
+// DoTimeout executes a command like Do, but stops waiting for it after the
+// timeout, returning ErrTimeout. The command itself keeps running. A timeout
+// of zero waits forever.
+func (q *commandQueue) DoTimeout(cmd func() error, timeout time.Duration) error {
+	if timeout <= 0 {
+		return q.Do(cmd)
+	}
+
+	result := make(chan error, 1)
+	if err := q.Enqueue(func() {
+		result <- cmd()
+	}); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return ErrTimeout
+	}
+}
+
+// Closed returns true if the queue has been closed.
+func (q *commandQueue) Closed() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.closed
+}
+
+// Close stops accepting new commands and waits for the queued ones to finish.
+func (q *commandQueue) Close() {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.cmds)
+	}
+	q.mutex.Unlock()
+
+	<-q.done
+}
+
+// pendingImage is an asynchronous image write which hasn't started yet.
+type pendingImage struct {
+	img     image.Image
+	results []chan error
+}
+
+// pendingImages tracks the asynchronous image writes waiting in the queue,
+// per button.
+type pendingImages struct {
+	sync.Mutex
+	images map[uint8]*pendingImage
+}
+
+// SetImageAsync sets the image of a button like SetImage, but returns
+// immediately. The result gets delivered on the returned channel once the
+// image has been sent. Asynchronous commands are executed one after another
+// in the order they were issued, even if the calls happen concurrently.
+//
+// If an image set with SetImageAsync for the same button is still waiting in
+// the queue, it gets replaced by the new one instead of being sent as well.
+// Both calls then receive the result of sending the new image. Only
+// asynchronous writes get coalesced like this, SetImage always sends its
+// image.
+func (d *Device) SetImageAsync(index uint8, img image.Image) <-chan error {
+	result := make(chan error, 1)
+	if !d.opened {
+		result <- ErrNotOpen
+		return result
+	}
+	if err := d.validateKey(index); err != nil {
+		result <- err
+		return result
+	}
+
+	p := d.pending
+	p.Lock()
+	if pi, ok := p.images[index]; ok {
+		pi.img = img
+		pi.results = append(pi.results, result)
+		p.Unlock()
+		return result
+	}
+
+	pi := &pendingImage{
+		img:     img,
+		results: []chan error{result},
+	}
+	p.images[index] = pi
+	p.Unlock()
+
+	// take the image out of the pending set once it's about to be sent, so
+	// later calls get queued again
+	take := func() (image.Image, []chan error) {
+		p.Lock()
+		defer p.Unlock()
+
+		delete(p.images, index)
+		return pi.img, pi.results
+	}
+
+	err := d.queue.Enqueue(func() {
+		img, results := take()
+		err := d.SetImage(index, img)
+		for _, r := range results {
+			r <- err
+		}
+	})
+	if err != nil {
+		_, results := take()
+		for _, r := range results {
+			r <- err
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,152 @@
+package streamdeck
+
+import (
+	"image/color"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCommandQueueSerializes(t *testing.T) {
+	q := newCommandQueue()
+	defer q.Close()
+
+	var active, overlaps int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = q.Do(func() error {
+				if atomic.AddInt32(&active, 1) > 1 {
+					atomic.AddInt32(&overlaps, 1)
+				}
+				defer atomic.AddInt32(&active, -1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if overlaps > 0 {
+		t.Errorf("%d commands ran concurrently with another one", overlaps)
+	}
+}
+
+func TestCommandQueueOrder(t *testing.T) {
+	q := newCommandQueue()
+
+	var order []int
+	for i := 0; i < 10; i++ {
+		i := i
+		if err := q.Enqueue(func() {
+			order = append(order, i)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close waits for the queued commands
+	q.Close()
+	for i, n := range order {
+		if i != n {
+			t.Fatalf("got commands in order %v", order)
+		}
+	}
+	if len(order) != 10 {
+		t.Errorf("got %d commands executed, expected 10", len(order))
+	}
+
+	if err := q.Enqueue(func() {}); err != ErrClosed {
+		t.Errorf("enqueueing after Close returned %v, expected ErrClosed", err)
+	}
+	if err := q.Do(func() error { return nil }); err != ErrClosed {
+		t.Errorf("Do after Close returned %v, expected ErrClosed", err)
+	}
+}
+
+// TestConcurrentIO needs to pass with the race detector enabled.
+func TestConcurrentIO(t *testing.T) {
+	d, f := newTestDevice(t)
+	f.SetFeature(append([]byte{0x05, 0x0c, 0x00, 0x00, 0x00, 0x00}, "1.00.008"...))
+
+	var wg sync.WaitGroup
+	for i := uint8(0); i < 5; i++ {
+		img := d.solidImage(color.RGBA{50 * i, 0, 0, 255})
+		wg.Add(3)
+		go func(index uint8) {
+			defer wg.Done()
+			if err := d.SetImage(index, img); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func(index uint8) {
+			defer wg.Done()
+			if err := <-d.SetImageAsync(index+5, img); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := d.FirmwareVersion(); err != nil {
+				t.Error(err)
+			}
+			if err := d.SendFeatureReport([]byte{0x03, 0x08, 50}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := f.Overlaps(); n > 0 {
+		t.Errorf("%d calls to the HID device overlapped", n)
+	}
+
+	// the pages of an image must not get interleaved with another image
+	cur := -1
+	for _, op := range f.Ops() {
+		if op.feature {
+			continue
+		}
+		key := int(op.data[2])
+		if cur >= 0 && key != cur {
+			t.Fatalf("pages of key %d got interleaved with key %d", cur, key)
+		}
+		cur = key
+		if op.data[3] == 1 {
+			cur = -1
+		}
+	}
+	if n := len(f.Images()); n != 10 {
+		t.Errorf("got %d images, expected 10", n)
+	}
+}
+
+func TestSetImageAsyncCoalesces(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	// hold up the queue, so all images for the button are pending at once
+	release := make(chan struct{})
+	if err := d.queue.Enqueue(func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	var results []<-chan error
+	for _, c := range colors {
+		results = append(results, d.SetImageAsync(0, d.solidImage(c)))
+	}
+	close(release)
+
+	for i, r := range results {
+		if err := <-r; err != nil {
+			t.Errorf("call %d: got error %v, expected nil", i, err)
+		}
+	}
+	if n := f.ImageWrites(0); n != 1 {
+		t.Fatalf("got %d image writes, expected one", n)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, colors[len(colors)-1]) {
+		t.Errorf("got color %v, expected the last image", c)
+	}
+}
@@ -0,0 +1,161 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// Scrolling text gets redrawn 25 times a second.
+	scrollFrameDelay = time.Second / 25
+)
+
+// ScrollOptions customizes how SetScrollingText renders and scrolls text.
+type ScrollOptions struct {
+	TextOptions
+
+	// Speed is the scrolling speed in pixels per second. It defaults to the
+	// key resolution per two seconds.
+	Speed float64
+	// Gap is the space in pixels between the end of the text and its start
+	// scrolling in again. It defaults to half the key resolution.
+	Gap int
+}
+
+// SetScrollingText shows a single line of text on a button, scrolling it to
+// the left in a loop if it's too wide to fit, like a marquee. Text that fits
+// is shown without scrolling. Calling stop, or setting another image on the
+// button, stops the scrolling; stop also resets the text to its start.
+func (d *Device) SetScrollingText(index uint8, text string, opts ScrollOptions) (stop func(), err error) {
+	if !d.opened {
+		return nil, ErrNotOpen
+	}
+
+	if err := d.validateKey(index); err != nil {
+		return nil, err
+	}
+
+	size := int(d.KeyPixels(index))
+	text = strings.Replace(text, "\n", " ", -1)
+	gap := opts.Gap
+	if gap <= 0 {
+		gap = size / 2
+	}
+	strip, err := renderTextStrip(size, text, gap, opts.TextOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// text which fits on the key is a single, static frame
+	width := strip.Bounds().Dx()
+	count := 1
+	var first image.Image
+	if width-gap <= size {
+		if first, err = renderText(size, text, opts.TextOptions); err != nil {
+			return nil, err
+		}
+	} else {
+		speed := opts.Speed
+		if speed <= 0 {
+			speed = float64(size) / 2
+		}
+		count = int(math.Round(float64(width) / (speed * scrollFrameDelay.Seconds())))
+		if count < 2 {
+			count = 2
+		}
+		first = marqueeFrame(strip, size, 0)
+	}
+
+	done, err := d.startFrames(index, count, func(i int) image.Image {
+		if i == 0 {
+			return first
+		}
+		return marqueeFrame(strip, size, i*width/count)
+	}, scrollFrameDelay, AnimationOptions{SkipFrames: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.writeMutex.Lock()
+			defer d.writeMutex.Unlock()
+
+			// don't touch the button if something else has been set on it
+			if done == nil || d.animations[index] != done {
+				return
+			}
+			d.stopAnimation(index)
+			_ = d.setImage(index, first, defaultImageOptions())
+		})
+	}, nil
+}
+
+// renderTextStrip renders a single line of text onto an image of the given
+// height, as wide as the text plus a trailing gap, with the text vertically
+// centered.
+func renderTextStrip(height int, text string, gap int, opts TextOptions) (*image.RGBA, error) {
+	fg := opts.Foreground
+	if fg == nil {
+		fg = color.White
+	}
+	bg := opts.Background
+	if bg == nil {
+		bg = color.Black
+	}
+
+	fontSize := opts.Size
+	if fontSize <= 0 {
+		fontSize = float64(height) / 5
+	}
+	f, err := textFace(opts.Font, fontSize)
+	if err != nil {
+		return nil, err
+	}
+	f.Lock()
+	defer f.Unlock()
+
+	metrics := f.face.Metrics()
+	width := font.MeasureString(f.face, text).Ceil() + gap
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(fg),
+		Face: f.face,
+		Dot: fixed.Point26_6{
+			X: 0,
+			Y: fixed.I(height-metrics.Height.Ceil())/2 + metrics.Ascent,
+		},
+	}
+	drawer.DrawString(text)
+	return img, nil
+}
+
+// marqueeFrame returns the square frame of the given size showing the strip
+// scrolled to the given offset. The strip wraps around, so the start of the
+// text follows its end.
+func marqueeFrame(strip *image.RGBA, size, offset int) *image.RGBA {
+	w := strip.Bounds().Dx()
+	offset %= w
+	if offset < 0 {
+		offset += w
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, size, size))
+	for x := -offset; x < size; x += w {
+		draw.Draw(frame, image.Rect(x, 0, x+w, size), strip, image.Point{}, draw.Src)
+	}
+	return frame
+}
@@ -0,0 +1,94 @@
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestMarqueeFrameWrapAround(t *testing.T) {
+	// a strip of ten columns, each with its own color
+	strip := image.NewRGBA(image.Rect(0, 0, 10, 4))
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 4; y++ {
+			strip.Set(x, y, color.RGBA{uint8(x), 0, 0, 255})
+		}
+	}
+
+	tt := []struct {
+		offset int
+		want   []uint8
+	}{
+		{0, []uint8{0, 1, 2, 3}},
+		{3, []uint8{3, 4, 5, 6}},
+		// the start of the strip follows its end
+		{8, []uint8{8, 9, 0, 1}},
+		{9, []uint8{9, 0, 1, 2}},
+		{10, []uint8{0, 1, 2, 3}},
+		{23, []uint8{3, 4, 5, 6}},
+		{-2, []uint8{8, 9, 0, 1}},
+	}
+
+	for _, test := range tt {
+		frame := marqueeFrame(strip, 4, test.offset)
+		for x, want := range test.want {
+			if c := frame.RGBAAt(x, 2); c.R != want {
+				t.Errorf("offset %d: got column %d at x=%d, expected %d", test.offset, c.R, x, want)
+			}
+		}
+	}
+}
+
+func TestSetScrollingText(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	stop, err := d.SetScrollingText(0, "a text much too long to fit on a single key", ScrollOptions{Speed: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return f.ImageWrites(0) >= 3
+	})
+
+	stop()
+	images := f.Images()
+	if first, last := images[0], images[len(images)-1]; !bytes.Equal(first.data, last.data) {
+		t.Error("stop didn't restore the start of the text")
+	}
+	// the scrolled frames don't replace the cached start of the text
+	n := len(images)
+	time.Sleep(3 * scrollFrameDelay)
+	if len(f.Images()) != n {
+		t.Error("text kept scrolling after stop")
+	}
+
+	// stopping again, or after another image got set, doesn't touch the key
+	stop()
+	if len(f.Images()) != n {
+		t.Error("stopping twice wrote to the key")
+	}
+}
+
+func TestSetScrollingTextFits(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	stop, err := d.SetScrollingText(0, "hi", ScrollOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	static, err := renderText(72, "hi", TextOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ki, ok := d.images.Get(0); !ok || !imagesEqual(ki.img, static) {
+		t.Error("fitting text didn't get cached as the key's image")
+	}
+
+	time.Sleep(3 * scrollFrameDelay)
+	stop()
+	if n := f.ImageWrites(0); n != 1 {
+		t.Errorf("got %d images for text which fits, expected one", n)
+	}
+}
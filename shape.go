@@ -0,0 +1,105 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// shapeKind is the kind of a KeyShape.
+type shapeKind int
+
+const (
+	shapeSquare shapeKind = iota
+	shapeRounded
+	shapeCircle
+)
+
+// KeyShape is the shape images get masked to before they are sent to a
+// button. Everything outside the shape is shown black, as the device can't
+// display transparency.
+type KeyShape struct {
+	kind   shapeKind
+	radius int
+}
+
+var (
+	// Square leaves images as they are.
+	Square = KeyShape{kind: shapeSquare}
+	// Circle masks images to the largest circle fitting on the key.
+	Circle = KeyShape{kind: shapeCircle}
+)
+
+// Rounded masks images to a square with corners rounded by the given radius
+// in pixels.
+func Rounded(radius int) KeyShape {
+	if radius <= 0 {
+		return Square
+	}
+	return KeyShape{kind: shapeRounded, radius: radius}
+}
+
+// SetKeyShape sets the shape all images get masked to, unless they are set
+// with WithKeyShape. It defaults to Square.
+func (d *Device) SetKeyShape(shape KeyShape) {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	d.keyShape = shape
+}
+
+// WithKeyShape masks the image to the given shape, rather than the one set
+// with SetKeyShape.
+func WithKeyShape(shape KeyShape) ImageOption {
+	return func(o *imageOptions) {
+		o.shape = shape
+		o.shapeSet = true
+	}
+}
+
+// applyKeyShape returns a copy of img with everything outside the shape faded
+// to black. The edges of the shape are anti-aliased.
+func applyKeyShape(img image.Image, shape KeyShape) image.Image {
+	b := img.Bounds()
+	size := math.Min(float64(b.Dx()), float64(b.Dy()))
+
+	var radius float64
+	switch shape.kind {
+	case shapeRounded:
+		radius = math.Min(float64(shape.radius), size/2)
+	case shapeCircle:
+		radius = size / 2
+	default:
+		return img
+	}
+
+	// the centers of the corner arcs span an inner rectangle
+	minX := float64(b.Min.X) + radius
+	maxX := float64(b.Max.X) - radius
+	minY := float64(b.Min.Y) + radius
+	maxY := float64(b.Max.Y) - radius
+
+	dst := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			px := math.Max(minX, math.Min(float64(x)+0.5, maxX))
+			py := math.Max(minY, math.Min(float64(y)+0.5, maxY))
+			dist := math.Hypot(float64(x)+0.5-px, float64(y)+0.5-py)
+
+			coverage := math.Max(0, math.Min(1, radius-dist+0.5))
+			if coverage == 0 {
+				dst.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+				continue
+			}
+
+			r, g, bl, _ := img.At(x, y).RGBA()
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(float64(r>>8) * coverage),
+				G: uint8(float64(g>>8) * coverage),
+				B: uint8(float64(bl>>8) * coverage),
+				A: 255,
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,96 @@
+package streamdeck
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+var update = flag.Bool("update", false, "update the golden images in testdata")
+
+// checkGolden compares img with the golden image of the given name in
+// testdata, or replaces the golden image when running with -update.
+func checkGolden(t *testing.T, name string, img image.Image) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".png")
+
+	if *update {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("cannot open golden image, run with -update to create it: %v", err)
+	}
+	defer f.Close()
+	golden, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imagesEqual(toRGBA(golden), toRGBA(img)) {
+		t.Errorf("image differs from %s, run with -update if the change is intended", path)
+	}
+}
+
+func TestKeyShapeGolden(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 72, 72))
+	draw.Draw(white, white.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	tt := []struct {
+		name  string
+		shape KeyShape
+	}{
+		{"shape_square", Square},
+		{"shape_rounded", Rounded(16)},
+		{"shape_rounded_large", Rounded(100)},
+		{"shape_circle", Circle},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			checkGolden(t, test.name, applyKeyShape(white, test.shape))
+		})
+	}
+
+	if Rounded(0) != Square {
+		t.Error("a zero radius isn't square")
+	}
+}
+
+func TestWithKeyShape(t *testing.T) {
+	d, f := newTestDevice(t)
+	white := d.solidImage(color.White)
+	d.SetKeyShape(Circle)
+
+	if err := d.SetImage(0, white); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImageOpt(1, white, WithKeyShape(Square)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the corners are outside the circle, but not outside the square
+	for index, want := range map[uint8]color.Color{0: color.Black, 1: color.White} {
+		img := f.LastImage(t, index)
+		if c := img.At(2, 2); !near(c, want) {
+			t.Errorf("key %d: got corner color %v, expected %v", index, c, want)
+		}
+		if c := img.At(36, 36); !near(c, color.White) {
+			t.Errorf("key %d: got center color %v, expected white", index, c)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package streamdeck
+
+import "time"
+
+// SetDeviceStandbyTimeout programs the firmware's own standby timeout, after
+// which the device turns off its screens by itself. Unlike SetSleepTimeout,
+// this keeps working when no application is running, but the host doesn't get
+// notified when it kicks in. None of the supported models are known to offer
+// this, so it currently returns ErrUnsupported.
+func (d *Device) SetDeviceStandbyTimeout(t time.Duration) error {
+	if !d.Capabilities().Has(CapStandbyTimeout) {
+		return ErrUnsupported
+	}
+
+	secs := uint32(t / time.Second)
+	report := make([]byte, len(d.standbyCommand)+4)
+	copy(report, d.standbyCommand)
+	report[len(d.standbyCommand)] = byte(secs)
+	report[len(d.standbyCommand)+1] = byte(secs >> 8)
+	report[len(d.standbyCommand)+2] = byte(secs >> 16)
+	report[len(d.standbyCommand)+3] = byte(secs >> 24)
+
+	return d.sendFeatureReport(cmdStandby, report)
+}
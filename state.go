@@ -0,0 +1,117 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+)
+
+// DeckState is a snapshot of the brightness and button images of a device.
+type DeckState struct {
+	Brightness uint8
+	Images     map[uint8]image.Image
+
+	opts map[uint8]imageOptions
+}
+
+// SaveState returns a snapshot of the device's current brightness and button
+// images, which can later be reapplied with RestoreState. The snapshot holds
+// copies of the images, so changing an image after setting it on a button
+// doesn't change the snapshot.
+func (d *Device) SaveState() DeckState {
+	s := DeckState{
+		Brightness: d.savedBrightness(),
+		Images:     make(map[uint8]image.Image),
+		opts:       make(map[uint8]imageOptions),
+	}
+
+	// the cache returns a decoded copy of every image, peeking keeps saving
+	// the state from changing which images get evicted
+	for i := uint8(0); i < d.Keys; i++ {
+		if ki, ok := d.images.Peek(i); ok {
+			s.Images[i] = ki.img
+			s.opts[i] = ki.opts
+		}
+	}
+	return s
+}
+
+// savedBrightness returns the brightness to save in a snapshot, which is the
+// brightness to wake up to while the device is asleep.
+func (d Device) savedBrightness() uint8 {
+	if d.asleep {
+		return d.preSleepBrightness
+	}
+	return d.brightness
+}
+
+// RestoreState reapplies a snapshot taken with SaveState. Only buttons whose
+// image differs from the snapshot get written, buttons without an image in
+// the snapshot are cleared.
+func (d *Device) RestoreState(s DeckState) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	black := d.solidImage(color.RGBA{0, 0, 0, 255})
+	for i := uint8(0); i < d.Keys; i++ {
+		ki, ok := keyImage{}, false
+		if img, exists := s.Images[i]; exists && img != nil {
+			ki, ok = newKeyImage(img), true
+			if opts, exists := s.opts[i]; exists {
+				ki.opts = opts
+			}
+		}
+
+		// a stopped animation may have left one of its frames on the button
+		animated := d.stopAnimation(i)
+		cached, isCached := d.images.Get(i)
+		if !ok {
+			if !isCached && !animated {
+				continue
+			}
+			ki = newKeyImage(black)
+		}
+		if isCached && !animated && cached.opts == ki.opts && imagesEqual(cached.img, ki.img) {
+			continue
+		}
+
+		if err := d.setImage(i, ki.img, ki.opts); err != nil {
+			return err
+		}
+	}
+
+	if s.Brightness != d.brightness {
+		return d.SetBrightness(s.Brightness)
+	}
+	return nil
+}
+
+// imagesEqual returns true if both images have the same bounds and pixels.
+func imagesEqual(a, b image.Image) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+
+	ra, okA := a.(*image.RGBA)
+	rb, okB := b.(*image.RGBA)
+	if okA && okB {
+		if ra.Stride == rb.Stride {
+			return string(ra.Pix) == string(rb.Pix)
+		}
+	}
+
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+	return true
+}
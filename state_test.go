@@ -0,0 +1,55 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+func TestSaveStateCopiesImages(t *testing.T) {
+	d, f := newTestDevice(t)
+	red := color.RGBA{255, 0, 0, 255}
+	img := d.solidImage(red)
+	if err := d.SetImage(0, img); err != nil {
+		t.Fatal(err)
+	}
+	s := d.SaveState()
+
+	// changing the image afterwards must not change the snapshot
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+	if err := d.SetImage(0, img); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.RestoreState(s); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, red) {
+		t.Errorf("got color %v after restoring, expected the saved red", c)
+	}
+}
+
+func TestSaveStateKeepsEvictionOrder(t *testing.T) {
+	d, _ := newTestDevice(t)
+	d.SetImageCacheSize(2)
+
+	// key 1 is the least recently used image
+	for _, i := range []uint8{1, 0} {
+		if err := d.SetImage(i, d.solidImage(color.RGBA{255, 0, 0, 255})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = d.SaveState()
+	if err := d.SetImage(2, d.solidImage(color.RGBA{0, 0, 255, 255})); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := d.images.Peek(1); ok {
+		t.Error("image of key 1 is still cached, expected it to be evicted")
+	}
+	if _, ok := d.images.Peek(0); !ok {
+		t.Error("image of key 0 got evicted, expected it to stay cached")
+	}
+}
@@ -0,0 +1,148 @@
+package streamdeck
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+)
+
+const (
+	// Version of the deck state file format written by SaveStateToFile.
+	stateFileVersion = 1
+	// Name of the manifest in a deck state file.
+	stateManifestName = "manifest.json"
+)
+
+// stateManifest describes the contents of a deck state file.
+type stateManifest struct {
+	Version    int     `json:"version"`
+	Brightness uint8   `json:"brightness"`
+	Keys       []uint8 `json:"keys"`
+}
+
+// SaveStateToFile writes a snapshot of the device's brightness and button
+// images, like SaveState takes, to a zip file at the given path. The images
+// get stored as PNG files. LoadStateFromFile reads the snapshot back.
+func (d *Device) SaveStateToFile(path string) (err error) {
+	s := d.SaveState()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	m := stateManifest{
+		Version:    stateFileVersion,
+		Brightness: s.Brightness,
+	}
+	for i := uint8(0); i < d.Keys; i++ {
+		img, ok := s.Images[i]
+		if !ok {
+			continue
+		}
+
+		w, err := zw.Create(stateImageName(i))
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(w, img); err != nil {
+			return fmt.Errorf("cannot encode image of key %d: %w", i, err)
+		}
+		m.Keys = append(m.Keys, i)
+	}
+
+	w, err := zw.Create(stateManifestName)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadStateFromFile reads a snapshot written by SaveStateToFile, which can be
+// applied with RestoreState. It fails for files written by a newer version of
+// this package.
+func LoadStateFromFile(path string) (DeckState, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return DeckState{}, err
+	}
+	defer zr.Close() //nolint:errcheck // r/o file
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[stateManifestName]
+	if !ok {
+		return DeckState{}, fmt.Errorf("%s is not a deck state file", path)
+	}
+	m, err := readStateManifest(mf)
+	if err != nil {
+		return DeckState{}, err
+	}
+	if m.Version > stateFileVersion {
+		return DeckState{}, fmt.Errorf("unsupported deck state version %d, expected at most %d", m.Version, stateFileVersion)
+	}
+
+	s := DeckState{
+		Brightness: m.Brightness,
+		Images:     make(map[uint8]image.Image, len(m.Keys)),
+	}
+	for _, i := range m.Keys {
+		f, ok := files[stateImageName(i)]
+		if !ok {
+			return DeckState{}, fmt.Errorf("deck state is missing the image of key %d", i)
+		}
+		img, err := readStateImage(f)
+		if err != nil {
+			return DeckState{}, fmt.Errorf("cannot decode image of key %d: %w", i, err)
+		}
+		s.Images[i] = img
+	}
+	return s, nil
+}
+
+// readStateManifest reads the manifest of a deck state file.
+func readStateManifest(f *zip.File) (stateManifest, error) {
+	var m stateManifest
+
+	r, err := f.Open()
+	if err != nil {
+		return m, err
+	}
+	defer r.Close() //nolint:errcheck // r/o file
+
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return m, fmt.Errorf("cannot read deck state manifest: %w", err)
+	}
+	return m, nil
+}
+
+// readStateImage reads a key image of a deck state file.
+func readStateImage(f *zip.File) (image.Image, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close() //nolint:errcheck // r/o file
+
+	return png.Decode(r)
+}
+
+// stateImageName returns the name of a key's image in a deck state file.
+func stateImageName(index uint8) string {
+	return fmt.Sprintf("keys/%d.png", index)
+}
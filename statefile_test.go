@@ -0,0 +1,112 @@
+package streamdeck
+
+import (
+	"archive/zip"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateFileRoundTrip(t *testing.T) {
+	d, _ := newTestDevice(t)
+	if err := d.SetBrightness(42); err != nil {
+		t.Fatal(err)
+	}
+	halves := halvesImage(72, 72)
+	if err := d.SetImage(3, halves); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImage(7, d.solidImage(color.RGBA{0, 255, 0, 255})); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "deck.zip")
+	if err := d.SaveStateToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadStateFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Brightness != 42 {
+		t.Errorf("got brightness %d, expected 42", s.Brightness)
+	}
+	if len(s.Images) != 2 {
+		t.Fatalf("got %d images, expected two", len(s.Images))
+	}
+	want := d.SaveState()
+	for _, i := range []uint8{3, 7} {
+		img, ok := s.Images[i]
+		if !ok {
+			t.Fatalf("image of key %d is missing", i)
+		}
+		// PNG is lossless, the images must match exactly
+		if !imagesEqual(img, want.Images[i]) {
+			t.Errorf("image of key %d changed in the round trip", i)
+		}
+	}
+
+	// the loaded state can be applied to another device
+	other, f := newTestDevice(t)
+	if err := other.RestoreState(s); err != nil {
+		t.Fatal(err)
+	}
+	if b := f.Brightnesses(); len(b) == 0 || b[len(b)-1] != 42 {
+		t.Errorf("got brightnesses %v, expected 42 last", b)
+	}
+	img := f.LastImage(t, 3)
+	for p, c := range map[image.Point]color.Color{
+		// the device sees the image rotated by 180 degrees
+		{10, 36}: color.RGBA{0, 0, 255, 255},
+		{60, 36}: color.RGBA{255, 0, 0, 255},
+	} {
+		if got := img.At(p.X, p.Y); !near(got, c) {
+			t.Errorf("got color %v at %v, expected %v", got, p, c)
+		}
+	}
+}
+
+func TestLoadStateFromFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeZip := func(name string, files map[string]string) string {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zw := zip.NewWriter(f)
+		for name, content := range files {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for name, files := range map[string]map[string]string{
+		"no manifest":   {"keys/0.png": ""},
+		"newer version": {stateManifestName: `{"version": 2}`},
+		"missing image": {stateManifestName: `{"version": 1, "keys": [0]}`},
+		"broken image":  {stateManifestName: `{"version": 1, "keys": [0]}`, "keys/0.png": "not a png"},
+	} {
+		if _, err := LoadStateFromFile(writeZip(name+".zip", files)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"image/color"
 	"image/jpeg"
 	"math"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -18,6 +19,20 @@ import (
 const (
 	// 30 fps fade animation.
 	fadeDelay = time.Second / 30
+	// Fade animations never run faster than 100 fps.
+	minFadeDelay = time.Second / 100
+	// Number of attempts to send a verified command.
+	verifyAttempts = 3
+	// Delay before the first retry of a verified command, doubling with
+	// every further retry.
+	retryDelay = 10 * time.Millisecond
+	// Retry delays are never shorter than 1ms.
+	minRetryDelay = time.Millisecond
+
+	// Size of the BMP file and bitmap info headers.
+	bmpHeaderSize = 54
+	// Upper bound for the size of the headers written by the JPEG encoder.
+	jpegHeaderSize = 1024
 )
 
 // Stream Deck Vendor & Product IDs.
@@ -38,14 +53,25 @@ const (
 //nolint:revive
 var (
 	c_REV1_FIRMWARE   = []byte{0x04}
+	c_REV1_SERIAL     = []byte{0x03}
 	c_REV1_RESET      = []byte{0x0b, 0x63}
 	c_REV1_BRIGHTNESS = []byte{0x05, 0x55, 0xaa, 0xd1, 0x01}
 
 	c_REV2_FIRMWARE   = []byte{0x05}
+	c_REV2_SERIAL     = []byte{0x06}
 	c_REV2_RESET      = []byte{0x03, 0x02}
 	c_REV2_BRIGHTNESS = []byte{0x03, 0x08}
 )
 
+// HIDDevice is the subset of hid.Device used to communicate with a device.
+type HIDDevice interface {
+	Read(b []byte) (int, error)
+	Write(b []byte) (int, error)
+	GetFeatureReport(b []byte) (int, error)
+	SendFeatureReport(b []byte) (int, error)
+	Close() error
+}
+
 // Device represents a single Stream Deck device.
 type Device struct {
 	ID     string
@@ -58,35 +84,102 @@ type Device struct {
 	DPI     uint
 	Padding uint
 
+	capabilities        Capability
 	featureReportSize   int
 	firmwareOffset      int
+	serialOffset        int
 	keyStateOffset      int
 	translateKeyIndex   func(index, columns uint8) uint8
+	keyPixels           func(index uint8) uint
+	parseKeyReport      func(d *Device, report []byte) []Key
+	parseEncoderReport  func(d *Device, report []byte) []Encoder
+	parseTouchReport    func(d *Device, report []byte) []Touch
+	touchStripImage     func(d *Device, img image.Image) error
 	imagePageSize       int
 	imagePageHeaderSize int
 	flipImage           func(image.Image) image.Image
-	toImageFormat       func(image.Image) ([]byte, error)
+	toImageFormat       func(img image.Image, quality int) ([]byte, error)
+	encodedImageSize    func(pixels uint) int
 	imagePageHeader     func(pageIndex int, keyIndex uint8, payloadLength int, lastPage bool) []byte
 
 	getFirmwareCommand   []byte
+	getSerialCommand     []byte
 	resetCommand         []byte
 	setBrightnessCommand []byte
+	standbyCommand       []byte
+	getProfileCommand    []byte
+	setProfileCommand    []byte
 
 	keyState []byte
-
-	device *hid.Device
+	pressed  []bool
+
+	inputMutex      *sync.Mutex
+	emergencyKeys   []uint8
+	emergencyAction func()
+	emergencyFired  bool
+	encoderEvents   chan Encoder
+	touchEvents     chan Touch
+	stateEvents     chan []bool
+	onDisconnect    func(err error)
+	holds           map[uint8]*holdConfirm
+	reading         bool
+	keyTrace        func(raw []byte, parsed []Key)
+	inputMode       InputMode
+	pollInterval    time.Duration
+
+	device HIDDevice
 	info   hid.DeviceInfo
+	opened bool
 
 	lastActionTime time.Time
 	asleep         bool
+	sleepMode      SleepMode
+	asleepMode     SleepMode
 	sleepCancel    context.CancelFunc
+	sleepPaused    bool
 	sleepMutex     *sync.RWMutex
 	fadeDuration   time.Duration
+	fadeInterval   time.Duration
+	clock          Clock
+	config         Config
 
 	brightness         uint8
 	preSleepBrightness uint8
+	verifyBrightness   bool
+	retryInterval      time.Duration
+
+	images     *imageCache
+	writeMutex *sync.Mutex
+	animations map[uint8]chan struct{}
+	names      *keyNames
+	transform  func(index uint8, img image.Image) image.Image
+	keyShape   KeyShape
+
+	metrics  *metrics
+	throttle *brightnessThrottle
+
+	brightnessHandler *brightnessHandler
+	queue             *commandQueue
+	pending           *pendingImages
+	io                *commandQueue
+	timeouts          *commandTimeouts
+	dryRun            bool
+	httpClient        *httpClient
 }
 
+// SleepMode determines what the device does when it is put to sleep.
+type SleepMode int
+
+// Sleep modes.
+const (
+	// DimOnly fades the brightness to zero.
+	DimOnly SleepMode = iota
+	// Blank fades the brightness to zero and clears all button images.
+	Blank
+	// ShowLogo resets the device, showing its standby logo.
+	ShowLogo
+)
+
 // Key holds the current status of a key on the device.
 type Key struct {
 	Index   uint8
@@ -99,106 +192,7 @@ func Devices() ([]Device, error) {
 
 	devs := hid.Enumerate(VID_ELGATO, 0)
 	for _, d := range devs {
-		var dev Device
-
-		switch {
-		case d.VendorID == VID_ELGATO && d.ProductID == PID_STREAMDECK:
-			dev = Device{
-				ID:                   d.Path,
-				Serial:               d.Serial,
-				Columns:              5,
-				Rows:                 3,
-				Keys:                 15,
-				Pixels:               72,
-				DPI:                  124,
-				Padding:              16,
-				featureReportSize:    17,
-				firmwareOffset:       5,
-				keyStateOffset:       1,
-				translateKeyIndex:    translateRightToLeft,
-				imagePageSize:        7819,
-				imagePageHeaderSize:  16,
-				imagePageHeader:      rev1ImagePageHeader,
-				flipImage:            flipHorizontally,
-				toImageFormat:        toBMP,
-				getFirmwareCommand:   c_REV1_FIRMWARE,
-				resetCommand:         c_REV1_RESET,
-				setBrightnessCommand: c_REV1_BRIGHTNESS,
-			}
-		case d.VendorID == VID_ELGATO && (d.ProductID == PID_STREAMDECK_MINI || d.ProductID == PID_STREAMDECK_MINI_MK2):
-			dev = Device{
-				ID:                   d.Path,
-				Serial:               d.Serial,
-				Columns:              3,
-				Rows:                 2,
-				Keys:                 6,
-				Pixels:               80,
-				DPI:                  138,
-				Padding:              16,
-				featureReportSize:    17,
-				firmwareOffset:       5,
-				keyStateOffset:       1,
-				translateKeyIndex:    identity,
-				imagePageSize:        1024,
-				imagePageHeaderSize:  16,
-				imagePageHeader:      miniImagePageHeader,
-				flipImage:            rotateCounterclockwise,
-				toImageFormat:        toBMP,
-				getFirmwareCommand:   c_REV1_FIRMWARE,
-				resetCommand:         c_REV1_RESET,
-				setBrightnessCommand: c_REV1_BRIGHTNESS,
-			}
-		case d.VendorID == VID_ELGATO && (d.ProductID == PID_STREAMDECK_V2 || d.ProductID == PID_STREAMDECK_MK2):
-			dev = Device{
-				ID:                   d.Path,
-				Serial:               d.Serial,
-				Columns:              5,
-				Rows:                 3,
-				Keys:                 15,
-				Pixels:               72,
-				DPI:                  124,
-				Padding:              16,
-				featureReportSize:    32,
-				firmwareOffset:       6,
-				keyStateOffset:       4,
-				translateKeyIndex:    identity,
-				imagePageSize:        1024,
-				imagePageHeaderSize:  8,
-				imagePageHeader:      rev2ImagePageHeader,
-				flipImage:            flipHorizontallyAndVertically,
-				toImageFormat:        toJPEG,
-				getFirmwareCommand:   c_REV2_FIRMWARE,
-				resetCommand:         c_REV2_RESET,
-				setBrightnessCommand: c_REV2_BRIGHTNESS,
-			}
-		case d.VendorID == VID_ELGATO && d.ProductID == PID_STREAMDECK_XL:
-			dev = Device{
-				ID:                   d.Path,
-				Serial:               d.Serial,
-				Columns:              8,
-				Rows:                 4,
-				Keys:                 32,
-				Pixels:               96,
-				DPI:                  166,
-				Padding:              16,
-				featureReportSize:    32,
-				firmwareOffset:       6,
-				keyStateOffset:       4,
-				translateKeyIndex:    identity,
-				imagePageSize:        1024,
-				imagePageHeaderSize:  8,
-				imagePageHeader:      rev2ImagePageHeader,
-				flipImage:            flipHorizontallyAndVertically,
-				toImageFormat:        toJPEG,
-				getFirmwareCommand:   c_REV2_FIRMWARE,
-				resetCommand:         c_REV2_RESET,
-				setBrightnessCommand: c_REV2_BRIGHTNESS,
-			}
-		}
-
-		if dev.ID != "" {
-			dev.keyState = make([]byte, dev.Columns*dev.Rows)
-			dev.info = d
+		if dev, ok := newDevice(d); ok {
 			dd = append(dd, dev)
 		}
 	}
@@ -206,41 +200,287 @@ func Devices() ([]Device, error) {
 	return dd, nil
 }
 
+// newDevice returns the Device for the given HID device, or false if it isn't
+// a supported Stream Deck.
+func newDevice(d hid.DeviceInfo) (Device, bool) {
+	var dev Device
+
+	switch {
+	case d.VendorID == VID_ELGATO && d.ProductID == PID_STREAMDECK:
+		dev = Device{
+			ID:                   d.Path,
+			Serial:               d.Serial,
+			Columns:              5,
+			Rows:                 3,
+			Keys:                 15,
+			Pixels:               72,
+			DPI:                  124,
+			Padding:              16,
+			capabilities:         CapLogoScreen,
+			featureReportSize:    17,
+			firmwareOffset:       5,
+			serialOffset:         5,
+			keyStateOffset:       1,
+			translateKeyIndex:    translateRightToLeft,
+			parseKeyReport:       parseKeyStates,
+			imagePageSize:        7819,
+			imagePageHeaderSize:  16,
+			imagePageHeader:      rev1ImagePageHeader,
+			flipImage:            flipHorizontally,
+			toImageFormat:        toBMP,
+			encodedImageSize:     bmpImageSize,
+			getFirmwareCommand:   c_REV1_FIRMWARE,
+			getSerialCommand:     c_REV1_SERIAL,
+			resetCommand:         c_REV1_RESET,
+			setBrightnessCommand: c_REV1_BRIGHTNESS,
+		}
+	case d.VendorID == VID_ELGATO && (d.ProductID == PID_STREAMDECK_MINI || d.ProductID == PID_STREAMDECK_MINI_MK2):
+		dev = Device{
+			ID:                   d.Path,
+			Serial:               d.Serial,
+			Columns:              3,
+			Rows:                 2,
+			Keys:                 6,
+			Pixels:               80,
+			DPI:                  138,
+			Padding:              16,
+			capabilities:         CapLogoScreen,
+			featureReportSize:    17,
+			firmwareOffset:       5,
+			serialOffset:         5,
+			keyStateOffset:       1,
+			translateKeyIndex:    identity,
+			parseKeyReport:       parseKeyStates,
+			imagePageSize:        1024,
+			imagePageHeaderSize:  16,
+			imagePageHeader:      miniImagePageHeader,
+			flipImage:            rotateCounterclockwise,
+			toImageFormat:        toBMP,
+			encodedImageSize:     bmpImageSize,
+			getFirmwareCommand:   c_REV1_FIRMWARE,
+			getSerialCommand:     c_REV1_SERIAL,
+			resetCommand:         c_REV1_RESET,
+			setBrightnessCommand: c_REV1_BRIGHTNESS,
+		}
+	case d.VendorID == VID_ELGATO && (d.ProductID == PID_STREAMDECK_V2 || d.ProductID == PID_STREAMDECK_MK2):
+		dev = Device{
+			ID:                   d.Path,
+			Serial:               d.Serial,
+			Columns:              5,
+			Rows:                 3,
+			Keys:                 15,
+			Pixels:               72,
+			DPI:                  124,
+			Padding:              16,
+			capabilities:         CapLogoScreen,
+			featureReportSize:    32,
+			firmwareOffset:       6,
+			serialOffset:         2,
+			keyStateOffset:       4,
+			translateKeyIndex:    identity,
+			parseKeyReport:       parseKeyStates,
+			imagePageSize:        1024,
+			imagePageHeaderSize:  8,
+			imagePageHeader:      rev2ImagePageHeader,
+			flipImage:            flipHorizontallyAndVertically,
+			toImageFormat:        toJPEG,
+			encodedImageSize:     jpegImageSize,
+			getFirmwareCommand:   c_REV2_FIRMWARE,
+			getSerialCommand:     c_REV2_SERIAL,
+			resetCommand:         c_REV2_RESET,
+			setBrightnessCommand: c_REV2_BRIGHTNESS,
+		}
+	case d.VendorID == VID_ELGATO && d.ProductID == PID_STREAMDECK_XL:
+		dev = Device{
+			ID:                   d.Path,
+			Serial:               d.Serial,
+			Columns:              8,
+			Rows:                 4,
+			Keys:                 32,
+			Pixels:               96,
+			DPI:                  166,
+			Padding:              16,
+			capabilities:         CapLogoScreen,
+			featureReportSize:    32,
+			firmwareOffset:       6,
+			serialOffset:         2,
+			keyStateOffset:       4,
+			translateKeyIndex:    identity,
+			parseKeyReport:       parseKeyStates,
+			imagePageSize:        1024,
+			imagePageHeaderSize:  8,
+			imagePageHeader:      rev2ImagePageHeader,
+			flipImage:            flipHorizontallyAndVertically,
+			toImageFormat:        toJPEG,
+			encodedImageSize:     jpegImageSize,
+			getFirmwareCommand:   c_REV2_FIRMWARE,
+			getSerialCommand:     c_REV2_SERIAL,
+			resetCommand:         c_REV2_RESET,
+			setBrightnessCommand: c_REV2_BRIGHTNESS,
+		}
+	default:
+		return Device{}, false
+	}
+
+	dev.keyState = make([]byte, dev.Columns*dev.Rows)
+	dev.pressed = make([]bool, dev.Keys)
+	dev.info = d
+
+	// everything but the I/O can be used before the device gets opened
+	dev.sleepMutex = &sync.RWMutex{}
+	dev.inputMutex = &sync.Mutex{}
+	dev.holds = make(map[uint8]*holdConfirm)
+	dev.images = newImageCache()
+	dev.writeMutex = &sync.Mutex{}
+	dev.animations = make(map[uint8]chan struct{})
+	dev.names = &keyNames{indexes: make(map[string]uint8)}
+	dev.metrics = newMetrics()
+	dev.throttle = &brightnessThrottle{interval: brightnessThrottleInterval}
+	dev.brightnessHandler = &brightnessHandler{}
+	dev.httpClient = &httpClient{}
+	dev.pending = &pendingImages{images: make(map[uint8]*pendingImage)}
+	dev.timeouts = &commandTimeouts{m: make(map[string]time.Duration)}
+	return dev, true
+}
+
+// NewDeviceWithHID returns the Device for the given HID device info, which
+// communicates through the given HIDDevice instead of opening the HID device
+// itself. This allows wrapping or replacing the HID layer, e.g. for testing.
+// Open still needs to be called before using the device.
+func NewDeviceWithHID(info hid.DeviceInfo, device HIDDevice) (*Device, error) {
+	dev, ok := newDevice(info)
+	if !ok {
+		return nil, fmt.Errorf("unsupported device %04x:%04x", info.VendorID, info.ProductID)
+	}
+
+	dev.device = device
+	return &dev, nil
+}
+
 // Open the device for input/output. This must be called before trying to
-// communicate with the device.
+// communicate with the device. A HIDDevice passed to NewDeviceWithHID is used
+// as it is, without opening the HID device, until the device gets closed.
+// Opening a device which is open already fails with ErrAlreadyOpen.
 func (d *Device) Open() error {
-	var err error
-	d.device, err = d.info.Open()
+	if d.opened {
+		return ErrAlreadyOpen
+	}
+
+	if d.device == nil {
+		device, err := d.info.Open()
+		if err != nil {
+			return err
+		}
+		d.device = device
+	}
+
+	// the device doesn't report its brightness, assume the firmware default
+	// so fades and sleep start from the right value
+	d.brightness = 100
 	d.lastActionTime = time.Now()
-	d.sleepMutex = &sync.RWMutex{}
-	return err
+	// the device may have been reset or replaced since it was last open
+	d.images.Clear()
+	d.queue = newCommandQueue()
+	d.io = newCommandQueue()
+	d.opened = true
+	d.applyConfig()
+	return nil
+}
+
+// OpenOptions configures the commands OpenWithOptions sends to the device
+// after opening it. The zero value leaves the device untouched, like Open.
+type OpenOptions struct {
+	// Clear clears all buttons after opening the device.
+	Clear bool
+	// SetBrightness sets the brightness to Brightness after opening the
+	// device. Otherwise the device keeps whatever brightness it had, which
+	// is assumed to be 100 percent.
+	SetBrightness bool
+	Brightness    uint8
+}
+
+// OpenWithOptions opens the device like Open does and sends the startup
+// commands configured in opts.
+func (d *Device) OpenWithOptions(opts OpenOptions) error {
+	if err := d.Open(); err != nil {
+		return err
+	}
+
+	if opts.SetBrightness {
+		if err := d.SetBrightness(opts.Brightness); err != nil {
+			return err
+		}
+	}
+	if opts.Clear {
+		return d.Clear()
+	}
+	return nil
 }
 
-// Close the connection with the device.
+// Close the connection with the device. Open reopens it, including a device
+// passed to NewDeviceWithHID, which then gets opened like any other device.
 func (d *Device) Close() error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
 	d.cancelSleepTimer()
-	return d.device.Close()
+	d.queue.Close()
+	d.io.Close()
+
+	device := d.device
+	d.device = nil
+	d.opened = false
+	return device.Close()
 }
 
 // FirmwareVersion returns the firmware version of the device.
 func (d Device) FirmwareVersion() (string, error) {
-	result, err := d.getFeatureReport(d.getFirmwareCommand)
+	if !d.opened {
+		return "", ErrNotOpen
+	}
+
+	result, err := d.getFeatureReport(cmdFirmware, d.getFirmwareCommand)
 	if err != nil {
 		return "", err
 	}
 	return string(result[d.firmwareOffset:]), nil
 }
 
+// ReadSerial queries the serial number from the device itself, rather than
+// relying on the one reported by the USB layer, and updates Serial with it.
+func (d *Device) ReadSerial() (string, error) {
+	if !d.opened {
+		return "", ErrNotOpen
+	}
+
+	result, err := d.getFeatureReport(cmdSerial, d.getSerialCommand)
+	if err != nil {
+		return "", err
+	}
+
+	serial := string(bytes.TrimRight(result[d.serialOffset:], "\x00"))
+	d.Serial = serial
+	return serial, nil
+}
+
 // Resets the Stream Deck, clears all button images and shows the standby image.
 func (d Device) Reset() error {
-	return d.sendFeatureReport(d.resetCommand)
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	return d.sendFeatureReport(cmdReset, d.resetCommand)
 }
 
 // Clears the Stream Deck, setting a black image on all buttons.
-func (d Device) Clear() error {
-	img := image.NewRGBA(image.Rect(0, 0, int(d.Pixels), int(d.Pixels)))
-	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 0, 255}), image.Point{}, draw.Src)
-	for i := uint8(0); i <= d.Columns*d.Rows; i++ {
+func (d *Device) Clear() error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	img := d.solidImage(color.RGBA{0, 0, 0, 255})
+	for i := uint8(0); i < d.Keys; i++ {
 		err := d.SetImage(i, img)
 		if err != nil {
 			fmt.Println(err)
@@ -251,16 +491,75 @@ func (d Device) Clear() error {
 	return nil
 }
 
+// FastClear clears the Stream Deck like Clear does, but encodes the black
+// image only once and sends the same data to every button.
+func (d *Device) FastClear() error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	img := d.solidImage(color.RGBA{0, 0, 0, 255})
+	opts := defaultImageOptions()
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	imageBytes, err := d.encodeImage(0, img, opts)
+	if err != nil {
+		return err
+	}
+
+	cached := newCachedImage(keyImage{img: img, opts: opts})
+	for i := uint8(0); i < d.Keys; i++ {
+		d.stopAnimation(i)
+		if err := d.writeImageData(i, imageBytes, opts); err != nil {
+			return err
+		}
+		d.images.SetCached(i, cached)
+	}
+
+	return nil
+}
+
 // ReadKeys returns a channel, which it will use to emit key presses/releases.
+// The channel gets closed when reading from the device fails, e.g. because it
+// got closed or disconnected. Until then, calling ReadKeys again returns
+// ErrAlreadyReading.
+//
+// The channel is unbuffered and must be drained continuously, just like every
+// channel returned by ReadKeyStates, ReadEncoders and ReadTouch. A channel
+// which isn't read from blocks the read loop, which then stops delivering
+// events on all of them.
 func (d *Device) ReadKeys() (chan Key, error) {
+	if !d.opened {
+		return nil, ErrNotOpen
+	}
+
+	d.inputMutex.Lock()
+	if d.reading {
+		d.inputMutex.Unlock()
+		return nil, ErrAlreadyReading
+	}
+	d.reading = true
+	d.inputMutex.Unlock()
+
 	kch := make(chan Key)
 	keyBuffer := make([]byte, d.keyStateOffset+len(d.keyState))
+	device, io := d.device, d.io
 	go func() {
 		for {
-			copy(d.keyState, keyBuffer[d.keyStateOffset:])
+			if err := d.readReport(device, io, keyBuffer); err != nil {
+				d.disconnected(err)
+
+				// ReadKeys can be called again once the channel is closed
+				d.inputMutex.Lock()
+				d.reading = false
+				d.inputMutex.Unlock()
 
-			if _, err := d.device.Read(keyBuffer); err != nil {
 				close(kch)
+				d.closeEncoders()
+				d.closeTouches()
+				d.closeKeyStates()
 				return
 			}
 
@@ -269,60 +568,204 @@ func (d *Device) ReadKeys() (chan Key, error) {
 				_ = d.Wake()
 
 				// reset state so no spurious key events get triggered
-				for i := d.keyStateOffset; i < len(keyBuffer); i++ {
-					keyBuffer[i] = 0
+				for i := range d.keyState {
+					d.keyState[i] = 0
 				}
 				continue
 			}
 
-			d.sleepMutex.Lock()
-			d.lastActionTime = time.Now()
-			d.sleepMutex.Unlock()
-
-			for i := d.keyStateOffset; i < len(keyBuffer); i++ {
-				keyIndex := uint8(i - d.keyStateOffset)
-				if keyBuffer[i] != d.keyState[keyIndex] {
-					kch <- Key{
-						Index:   d.translateKeyIndex(keyIndex, d.Columns),
-						Pressed: keyBuffer[i] == 1,
-					}
-				}
+			d.NotifyActivity()
+
+			keys := d.parseKeyReport(d, keyBuffer)
+			d.traceKeys(keyBuffer, keys)
+			state := d.trackKeys(keys)
+			for _, k := range keys {
+				kch <- k
 			}
+			d.emitKeyStates(state)
+			d.emitEncoders(keyBuffer)
+			d.emitTouches(keyBuffer)
 		}
 	}()
 
 	return kch, nil
 }
 
-// Sleep puts the device asleep, waiting for a key event to wake it up.
+// Sleep puts the device asleep, waiting for a key event to wake it up. It does
+// nothing if the device is already asleep.
 func (d *Device) Sleep() error {
-	d.sleepMutex.Lock()
-	defer d.sleepMutex.Unlock()
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	defer d.lockSleep()()
+
+	// sleeping again would overwrite the brightness to restore on wake
+	if d.asleep {
+		return nil
+	}
 
 	d.preSleepBrightness = d.brightness
+	d.asleepMode = d.sleepMode
+
+	if d.asleepMode == ShowLogo {
+		d.asleep = true
+		return d.Reset()
+	}
 
 	if err := d.Fade(d.brightness, 0, d.fadeDuration); err != nil {
 		return err
 	}
 
 	d.asleep = true
-	return d.SetBrightness(0)
+	if err := d.SetBrightness(0); err != nil {
+		return err
+	}
+
+	if d.asleepMode == Blank {
+		return d.blank()
+	}
+	return nil
 }
 
-// Wake wakes the device from sleep.
+// Wake wakes the device from sleep. It does nothing if the device isn't
+// asleep.
 func (d *Device) Wake() error {
-	d.sleepMutex.Lock()
-	defer d.sleepMutex.Unlock()
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	defer d.lockSleep()()
+
+	if !d.asleep {
+		return nil
+	}
 
 	d.asleep = false
-	if err := d.Fade(0, d.preSleepBrightness, d.fadeDuration); err != nil {
-		return err
+	if d.asleepMode != DimOnly {
+		if err := d.restoreImages(); err != nil {
+			return err
+		}
+	}
+
+	if d.asleepMode != ShowLogo {
+		if err := d.Fade(0, d.preSleepBrightness, d.fadeDuration); err != nil {
+			return err
+		}
 	}
 
 	d.lastActionTime = time.Now()
 	return d.SetBrightness(d.preSleepBrightness)
 }
 
+// Resume sets the given images with the lights off and then fades the
+// brightness up, so no old content flashes up before the new images are
+// shown. It wakes the device if it's asleep, restoring the cached images of
+// the buttons not in images after a Blank or ShowLogo sleep.
+func (d *Device) Resume(images map[uint8]image.Image, brightness uint8) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	for index, img := range images {
+		if err := d.validateKeyImage(index, img); err != nil {
+			return err
+		}
+	}
+
+	defer d.lockSleep()()
+
+	wasAsleep, mode := d.asleep, d.asleepMode
+	d.asleep = false
+
+	if !wasAsleep || mode == ShowLogo {
+		if err := d.SetBrightness(0); err != nil {
+			return err
+		}
+	}
+
+	if wasAsleep && mode != DimOnly {
+		// the buttons got blanked, so all of them need to be written
+		d.writeMutex.Lock()
+		for index, img := range images {
+			d.stopAnimation(index)
+			d.images.Set(index, newKeyImage(img))
+		}
+		d.writeMutex.Unlock()
+
+		if err := d.restoreImages(); err != nil {
+			return err
+		}
+	} else if err := d.SetImagesFunc(func(index uint8) (image.Image, error) {
+		return images[index], nil
+	}); err != nil {
+		return err
+	}
+
+	if err := d.Fade(0, brightness, d.fadeDuration); err != nil {
+		return err
+	}
+
+	d.lastActionTime = time.Now()
+	return d.SetBrightness(brightness)
+}
+
+// lockSleep takes the sleep lock for putting the device to sleep or waking it,
+// and returns the function releasing it. Brightness changes made meanwhile get
+// notified only after the lock got released, so the handler can call methods
+// which take it, such as IncreaseBrightness or NotifyActivity.
+func (d *Device) lockSleep() func() {
+	d.brightnessHandler.Hold()
+	d.sleepMutex.Lock()
+
+	return func() {
+		d.sleepMutex.Unlock()
+		d.brightnessHandler.Release()
+	}
+}
+
+// SetSleepMode sets what the device does when it is put to sleep. The default
+// is DimOnly.
+func (d *Device) SetSleepMode(mode SleepMode) {
+	d.sleepMutex.Lock()
+	defer d.sleepMutex.Unlock()
+
+	d.sleepMode = mode
+}
+
+// blank sets a black image on all buttons, while keeping their cached images.
+func (d *Device) blank() error {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	img := d.solidImage(color.RGBA{0, 0, 0, 255})
+	for i := uint8(0); i < d.Keys; i++ {
+		if err := d.writeImage(i, img, defaultImageOptions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreImages writes the cached images back to all buttons. Buttons without
+// a cached image are set to black.
+func (d *Device) restoreImages() error {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	black := newKeyImage(d.solidImage(color.RGBA{0, 0, 0, 255}))
+	for i := uint8(0); i < d.Keys; i++ {
+		ki, ok := d.images.Get(i)
+		if !ok {
+			ki = black
+		}
+		if err := d.writeImage(i, ki.img, ki.opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Asleep returns true if the device is asleep.
 func (d Device) Asleep() bool {
 	return d.asleep
@@ -343,6 +786,17 @@ func (d *Device) SetSleepFadeDuration(t time.Duration) {
 	d.fadeDuration = t
 }
 
+// NotifyActivity resets the sleep timeout, just like a key event does. Call it
+// whenever the user is active elsewhere, e.g. based on the operating system's
+// idle time or input events, to keep the device awake while the computer is in
+// use. It doesn't wake up a device that is already asleep.
+func (d *Device) NotifyActivity() {
+	d.sleepMutex.Lock()
+	defer d.sleepMutex.Unlock()
+
+	d.lastActionTime = time.Now()
+}
+
 // SetSleepTimeout sets the time after which the device will sleep if no key
 // events are received.
 func (d *Device) SetSleepTimeout(t time.Duration) {
@@ -355,16 +809,31 @@ func (d *Device) SetSleepTimeout(t time.Duration) {
 	ctx, d.sleepCancel = context.WithCancel(context.Background())
 
 	go func() {
+		timer := time.NewTimer(t)
+		defer timer.Stop()
+
 		for {
 			select {
-			case <-time.After(time.Second):
+			case <-timer.C:
 				d.sleepMutex.RLock()
-				since := time.Since(d.lastActionTime)
+				remaining := t - time.Since(d.lastActionTime)
+				paused := d.sleepPaused
 				d.sleepMutex.RUnlock()
 
-				if !d.asleep && since >= t {
+				if paused {
+					timer.Reset(t)
+					continue
+				}
+				// activity since the timer got armed pushes the deadline back
+				if remaining > 0 {
+					timer.Reset(remaining)
+					continue
+				}
+
+				if !d.asleep {
 					_ = d.Sleep()
 				}
+				timer.Reset(t)
 
 			case <-ctx.Done():
 				return
@@ -373,34 +842,102 @@ func (d *Device) SetSleepTimeout(t time.Duration) {
 	}()
 }
 
+// PauseSleep keeps the sleep timeout from putting the device to sleep, e.g.
+// during a presentation, until ResumeSleep gets called. Unlike disabling the
+// timeout, this keeps it configured.
+func (d *Device) PauseSleep() {
+	d.sleepMutex.Lock()
+	defer d.sleepMutex.Unlock()
+
+	d.sleepPaused = true
+}
+
+// ResumeSleep lets the sleep timeout put the device to sleep again, after it
+// got paused with PauseSleep. The timeout starts counting from now.
+func (d *Device) ResumeSleep() {
+	d.sleepMutex.Lock()
+	defer d.sleepMutex.Unlock()
+
+	d.sleepPaused = false
+	d.lastActionTime = time.Now()
+}
+
 // Fade fades the brightness in or out.
 func (d *Device) Fade(start uint8, end uint8, duration time.Duration) error {
-	step := (float64(end) - float64(start)) / float64(duration/fadeDelay)
-	if step == math.Inf(1) || step == math.Inf(-1) {
+	if start == end {
 		return nil
 	}
 
-	for current := float64(start); ; current += step {
-		if !((start < end && int8(current) < int8(end)) ||
-			(start > end && int8(current) > int8(end))) {
-			break
-		}
-		if err := d.SetBrightness(uint8(current)); err != nil {
-			return err
+	old := d.brightness
+	perStep := d.brightnessHandler.PerStep()
+
+	clock := d.getClock()
+	delay := d.fadeDelay()
+	step := (float64(end) - float64(start)) / float64(duration/delay)
+	if step != math.Inf(1) && step != math.Inf(-1) {
+		for current := float64(start); ; current += step {
+			if !((start < end && int8(current) < int8(end)) ||
+				(start > end && int8(current) > int8(end))) {
+				break
+			}
+			if err := d.setBrightness(uint8(current), perStep); err != nil {
+				return err
+			}
+
+			clock.Sleep(delay)
 		}
+	}
 
-		time.Sleep(fadeDelay)
+	if err := d.setBrightness(end, perStep); err != nil {
+		return err
+	}
+	if !perStep {
+		d.brightnessHandler.Notify(old, end)
 	}
 	return nil
 }
 
+// SetFadeDelay sets the interval between two brightness steps of a fade
+// animation. Shorter delays result in smoother fades, but send more commands
+// to the device. Delays shorter than 10ms are raised to 10ms.
+func (d *Device) SetFadeDelay(t time.Duration) {
+	if t < minFadeDelay {
+		t = minFadeDelay
+	}
+	d.fadeInterval = t
+}
+
+// fadeDelay returns the interval between two brightness steps of a fade
+// animation.
+func (d Device) fadeDelay() time.Duration {
+	if d.fadeInterval == 0 {
+		return fadeDelay
+	}
+	return d.fadeInterval
+}
+
 // SetBrightness sets the background lighting brightness from 0 to 100 percent.
 func (d *Device) SetBrightness(percent uint8) error {
+	return d.setBrightness(percent, true)
+}
+
+// setBrightness sets the brightness and optionally notifies the brightness
+// change handler.
+func (d *Device) setBrightness(percent uint8, notify bool) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
 	if percent > 100 {
 		percent = 100
 	}
 
+	old := d.brightness
 	d.brightness = percent
+	if notify {
+		defer d.brightnessHandler.Notify(old, percent)
+	}
+
 	if d.asleep && percent > 0 {
 		// if the device is asleep, remember the brightness, but don't set it
 		d.sleepMutex.Lock()
@@ -413,22 +950,99 @@ func (d *Device) SetBrightness(percent uint8) error {
 	copy(report, d.setBrightnessCommand)
 	report[len(report)-1] = percent
 
-	return d.sendFeatureReport(report)
+	if d.verifyBrightness {
+		return d.sendVerifiedFeatureReport(cmdBrightness, report)
+	}
+	return d.sendFeatureReport(cmdBrightness, report)
+}
+
+// SetBrightnessVerify enables verifying that brightness changes got accepted by
+// the device, retrying them otherwise. The firmware doesn't report the current
+// brightness back, so a change counts as accepted if the device took the whole
+// feature report without an error.
+func (d *Device) SetBrightnessVerify(verify bool) {
+	d.verifyBrightness = verify
 }
 
 // SetImage sets the image of a button on the Stream Deck. The provided image
 // needs to be in the correct resolution for the device. The index starts with
-// 0 being the top-left button.
-func (d Device) SetImage(index uint8, img image.Image) error {
-	if img.Bounds().Dy() != int(d.Pixels) ||
-		img.Bounds().Dx() != int(d.Pixels) {
-		return fmt.Errorf("supplied image has wrong dimensions, expected %[1]dx%[1]d pixels", d.Pixels)
+// 0 being the top-left button. Any animation running on the button, such as a
+// Blink, gets stopped.
+func (d *Device) SetImage(index uint8, img image.Image) error {
+	return d.SetImageOpt(index, img)
+}
+
+// setImage writes the image to a button and caches it. While the device is
+// asleep with its images blanked, the image only gets cached and will be shown
+// once the device wakes up. The caller must hold the writeMutex.
+func (d *Device) setImage(index uint8, img image.Image, opts imageOptions) error {
+	if err := d.showImage(index, img, opts); err != nil {
+		return err
 	}
 
-	imageBytes, err := d.toImageFormat(d.flipImage(img))
+	// the image may be restored later on, long after the context is gone
+	opts.ctx = nil
+	d.images.Set(index, keyImage{img: img, opts: opts})
+	return nil
+}
+
+// showImage writes the image to a button like setImage does, without caching
+// it. The caller must hold the writeMutex.
+func (d *Device) showImage(index uint8, img image.Image, opts imageOptions) error {
+	if d.asleep && d.asleepMode != DimOnly && !opts.force {
+		return d.validateKeyImage(index, img)
+	}
+	return d.writeImage(index, img, opts)
+}
+
+// writeImage writes the image to a button without caching it. The caller must
+// hold the writeMutex.
+func (d *Device) writeImage(index uint8, img image.Image, opts imageOptions) error {
+	imageBytes, err := d.encodeImage(index, img, opts)
+	if err != nil {
+		return err
+	}
+
+	return d.writeImageData(index, imageBytes, opts)
+}
+
+// encodeImage validates the image and converts it to the device's image
+// format.
+func (d Device) encodeImage(index uint8, img image.Image, opts imageOptions) ([]byte, error) {
+	if err := d.validateKeyImage(index, img); err != nil {
+		return nil, err
+	}
+
+	if d.transform != nil {
+		img = d.transform(index, img)
+		if err := d.validateKeyImage(index, img); err != nil {
+			return nil, fmt.Errorf("image transform changed the image: %w", err)
+		}
+	}
+	if opts.background.A > 0 {
+		img = flattenImage(img, opts.background)
+	}
+	shape := d.keyShape
+	if opts.shapeSet {
+		shape = opts.shape
+	}
+	img = applyKeyShape(img, shape)
+	if opts.badge > 0 {
+		img = drawBadge(img, opts.badge)
+	}
+	if opts.flip {
+		img = d.flipImage(img)
+	}
+	imageBytes, err := d.toImageFormat(img, opts.quality)
 	if err != nil {
-		return fmt.Errorf("cannot convert image data: %v", err)
+		return nil, fmt.Errorf("cannot convert image data: %v", err)
 	}
+	return imageBytes, nil
+}
+
+// writeImageData writes image data, already in the device's image format, to a
+// button. The caller must hold the writeMutex.
+func (d *Device) writeImageData(index uint8, imageBytes []byte, opts imageOptions) error {
 	imageData := imageData{
 		image:    imageBytes,
 		pageSize: d.imagePageSize - d.imagePageHeaderSize,
@@ -436,9 +1050,16 @@ func (d Device) SetImage(index uint8, img image.Image) error {
 
 	data := make([]byte, d.imagePageSize)
 
+	var written int
 	var page int
 	var lastPage bool
 	for !lastPage {
+		if opts.ctx != nil {
+			if err := opts.ctx.Err(); err != nil {
+				return err
+			}
+		}
+
 		var payload []byte
 		payload, lastPage = imageData.Page(page)
 		header := d.imagePageHeader(page, d.translateKeyIndex(index, d.Columns), len(payload), lastPage)
@@ -446,8 +1067,10 @@ func (d Device) SetImage(index uint8, img image.Image) error {
 		copy(data, header)
 		copy(data[len(header):], payload)
 
-		_, err := d.device.Write(data)
+		n, err := d.write(cmdImage, data)
+		written += n
 		if err != nil {
+			d.metrics.record(cmdImage, written, err)
 			return fmt.Errorf("cannot write image page %d of %d (%d image bytes) %d bytes: %v",
 				page, imageData.PageCount(), imageData.Length(), len(data), err)
 		}
@@ -455,15 +1078,132 @@ func (d Device) SetImage(index uint8, img image.Image) error {
 		page++
 	}
 
+	d.metrics.record(cmdImage, written, nil)
+	return nil
+}
+
+// ValidateImage checks that the image has the correct resolution for the
+// device, without sending it. It returns a *DimensionError otherwise.
+func (d Device) ValidateImage(img image.Image) error {
+	return validateImageSize(img, d.Pixels)
+}
+
+// validateKeyImage checks that the image has the correct resolution for the
+// given button.
+func (d Device) validateKeyImage(index uint8, img image.Image) error {
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+	return validateImageSize(img, d.KeyPixels(index))
+}
+
+// IsValidKey returns true if the index refers to one of the device's buttons.
+func (d Device) IsValidKey(index uint8) bool {
+	return index < d.Keys
+}
+
+// validateKey returns an error wrapping ErrKeyOutOfRange if the index doesn't
+// refer to one of the device's buttons.
+func (d Device) validateKey(index uint8) error {
+	if !d.IsValidKey(index) {
+		return fmt.Errorf("%w: key %d, device has %d keys", ErrKeyOutOfRange, index, d.Keys)
+	}
+	return nil
+}
+
+// validateImageSize checks that the image is a square of the given size.
+func validateImageSize(img image.Image, pixels uint) error {
+	if img.Bounds().Dy() != int(pixels) ||
+		img.Bounds().Dx() != int(pixels) {
+		return &DimensionError{
+			Width:    img.Bounds().Dx(),
+			Height:   img.Bounds().Dy(),
+			Expected: pixels,
+		}
+	}
 	return nil
 }
 
+// KeyPixels returns the resolution of the given button. All buttons of the
+// currently supported models have the same resolution, Pixels.
+func (d Device) KeyPixels(index uint8) uint {
+	if d.keyPixels != nil {
+		return d.keyPixels(index)
+	}
+	return d.Pixels
+}
+
+// solidImage returns an image in the device's key resolution, filled with the
+// given color.
+func (d Device) solidImage(c color.Color) *image.RGBA {
+	img := d.NewKeyImage()
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// EncodedImageSize returns the size in bytes of a key image encoded in the
+// device's image format. For fixed-size formats like BMP this is the exact
+// size, for JPEG it is a conservative upper bound.
+func (d Device) EncodedImageSize() int {
+	return d.encodedImageSize(d.Pixels)
+}
+
+// TransferChunkSize returns the size in bytes of the reports image data gets
+// split into when it's sent to the device, including each page's header.
+func (d Device) TransferChunkSize() int {
+	return d.imagePageSize
+}
+
+// GetFeatureReport reads the feature report with the given ID and size from
+// the device. The returned report starts with the report ID. This is meant for
+// exploring the device's protocol; regular applications shouldn't need it.
+func (d Device) GetFeatureReport(reportID byte, size int) ([]byte, error) {
+	if !d.opened {
+		return nil, ErrNotOpen
+	}
+
+	if size < 1 {
+		return nil, fmt.Errorf("invalid feature report size %d", size)
+	}
+
+	b := make([]byte, size)
+	b[0] = reportID
+	_, err := d.do(cmdRaw, func() (int, error) {
+		return d.device.GetFeatureReport(b)
+	})
+	d.metrics.record(cmdRaw, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SendFeatureReport sends a raw feature report to the device. The report must
+// start with its report ID. This is meant for exploring the device's protocol;
+// regular applications shouldn't need it.
+func (d Device) SendFeatureReport(report []byte) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	n, err := d.sendReport(cmdRaw, report)
+	d.metrics.record(cmdRaw, n, err)
+	return err
+}
+
 // getFeatureReport from the device without worries about the correct payload
 // size.
-func (d Device) getFeatureReport(payload []byte) ([]byte, error) {
+func (d Device) getFeatureReport(cmd string, payload []byte) ([]byte, error) {
+	if !d.opened {
+		return nil, ErrNotOpen
+	}
+
 	b := make([]byte, d.featureReportSize)
 	copy(b, payload)
-	_, err := d.device.GetFeatureReport(b)
+	_, err := d.do(cmd, func() (int, error) {
+		return d.device.GetFeatureReport(b)
+	})
+	d.metrics.record(cmd, 0, err)
 	if err != nil {
 		return nil, err
 	}
@@ -472,13 +1212,143 @@ func (d Device) getFeatureReport(payload []byte) ([]byte, error) {
 
 // sendFeatureReport to the device without worries about the correct payload
 // size.
-func (d Device) sendFeatureReport(payload []byte) error {
+func (d Device) sendFeatureReport(cmd string, payload []byte) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
 	b := make([]byte, d.featureReportSize)
 	copy(b, payload)
-	_, err := d.device.SendFeatureReport(b)
+	n, err := d.sendReport(cmd, b)
+	d.metrics.record(cmd, n, err)
 	return err
 }
 
+// SetDryRun enables or disables dry-run mode. In dry-run mode, commands that
+// would change the device's state are processed and counted in the Metrics as
+// usual, but never sent to the device. Reading from the device still works.
+func (d *Device) SetDryRun(dryRun bool) {
+	d.dryRun = dryRun
+}
+
+// write sends data for a command to the device on the I/O goroutine.
+func (d Device) write(cmd string, data []byte) (int, error) {
+	if !d.opened {
+		return 0, ErrNotOpen
+	}
+	if d.dryRun {
+		return len(data), nil
+	}
+
+	return d.do(cmd, func() (int, error) {
+		start := time.Now()
+		n, err := d.device.Write(data)
+		d.metrics.latency(time.Since(start))
+		return n, err
+	})
+}
+
+// sendReport sends a raw feature report for a command to the device on the
+// I/O goroutine.
+func (d Device) sendReport(cmd string, b []byte) (int, error) {
+	if !d.opened {
+		return 0, ErrNotOpen
+	}
+	if d.dryRun {
+		return len(b), nil
+	}
+
+	return d.do(cmd, func() (int, error) {
+		return d.device.SendFeatureReport(b)
+	})
+}
+
+// sendVerifiedFeatureReport sends a feature report like sendFeatureReport, but
+// retries if sending it fails or the device doesn't accept the whole report.
+func (d Device) sendVerifiedFeatureReport(cmd string, payload []byte) error {
+	b := make([]byte, d.featureReportSize)
+	copy(b, payload)
+
+	var err error
+	for attempt := 0; attempt < verifyAttempts; attempt++ {
+		if attempt > 0 {
+			d.metrics.retry(cmd, uint(attempt), err)
+			d.getClock().Sleep(d.backoff(attempt))
+		}
+
+		var n int
+		n, err = d.sendReport(cmd, b)
+		if err == nil && n < len(b) {
+			err = fmt.Errorf("device accepted only %d of %d bytes", n, len(b))
+		}
+		d.metrics.record(cmd, n, err)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot send %s command after %d attempts: %v", cmd, verifyAttempts, err)
+}
+
+// SetRetryDelay sets the delay before retrying a verified command for the
+// first time. Every further retry waits twice as long as the one before, plus
+// some random jitter, to give a struggling device time to recover. Delays
+// shorter than 1ms are raised to 1ms.
+func (d *Device) SetRetryDelay(t time.Duration) {
+	if t < minRetryDelay {
+		t = minRetryDelay
+	}
+	d.retryInterval = t
+}
+
+// backoff returns the delay before the given retry attempt, starting with 1.
+func (d Device) backoff(attempt int) time.Duration {
+	delay := d.retryInterval
+	if delay == 0 {
+		delay = retryDelay
+	}
+	delay <<= uint(attempt - 1)
+
+	// up to 50% jitter, so several devices don't retry in lockstep
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// parseKeyStates parses a report containing the state of every key, one byte
+// per key. It diffs the report against the previously known key states and
+// returns an event for each key that changed. Malformed reports never yield
+// events for keys the device doesn't have.
+func parseKeyStates(d *Device, report []byte) []Key {
+	if d.Columns == 0 {
+		return nil
+	}
+
+	var keys []Key
+	for i := d.keyStateOffset; i < len(report); i++ {
+		// don't convert to uint8 before checking the bounds, overlong
+		// reports would wrap around to the first keys otherwise
+		offset := i - d.keyStateOffset
+		if offset >= len(d.keyState) || offset >= int(d.Keys) {
+			break
+		}
+
+		keyIndex := uint8(offset)
+		if report[i] == d.keyState[keyIndex] {
+			continue
+		}
+		d.keyState[keyIndex] = report[i]
+
+		index := d.translateKeyIndex(keyIndex, d.Columns)
+		if !d.IsValidKey(index) {
+			continue
+		}
+		keys = append(keys, Key{
+			Index:   index,
+			Pressed: report[i] == 1,
+		})
+	}
+	return keys
+}
+
 // translateRightToLeft translates the given key index from right-to-left to
 // left-to-right, based on the given number of columns.
 func translateRightToLeft(index, columns uint8) uint8 {
@@ -556,8 +1426,9 @@ func rotateCounterclockwise(img image.Image) image.Image {
 	return flipped
 }
 
-// toBMP returns the raw bytes of the given image in BMP format.
-func toBMP(img image.Image) ([]byte, error) {
+// toBMP returns the raw bytes of the given image in BMP format. BMP is
+// lossless, so the quality is ignored.
+func toBMP(img image.Image, _ int) ([]byte, error) {
 	rgba := toRGBA(img)
 
 	// this is a BMP file header followed by a BPM bitmap info header
@@ -588,17 +1459,45 @@ func toBMP(img image.Image) ([]byte, error) {
 	return buffer, nil
 }
 
-// toJPEG returns the raw bytes of the given image in JPEG format.
-func toJPEG(img image.Image) ([]byte, error) {
-	buffer := bytes.NewBuffer([]byte{})
+// toJPEG returns the raw bytes of the given image in JPEG format, encoded with
+// the given quality.
+func toJPEG(img image.Image, quality int) ([]byte, error) {
+	buffer := jpegBuffers.Get().(*bytes.Buffer)
+	defer jpegBuffers.Put(buffer)
+	buffer.Reset()
+
 	opts := jpeg.Options{
-		Quality: 100,
+		Quality: quality,
 	}
 	err := jpeg.Encode(buffer, img, &opts)
 	if err != nil {
 		return nil, err
 	}
-	return buffer.Bytes(), err
+
+	// the buffer goes back to the pool, so hand out a copy of its contents
+	return append([]byte(nil), buffer.Bytes()...), nil
+}
+
+// jpegBuffers holds buffers for encoding JPEG images, so animations and full
+// deck refreshes don't grow a new buffer for every image.
+var jpegBuffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// bmpImageSize returns the exact size of a square image with the given
+// resolution in BMP format.
+func bmpImageSize(pixels uint) int {
+	return bmpHeaderSize + int(pixels*pixels)*3
+}
+
+// jpegImageSize returns the maximum size of a square image with the given
+// resolution in JPEG format. Even at full quality the Go encoder, which uses
+// 4:2:0 chroma subsampling, stays well below the size of the raw RGB data, so
+// that plus some room for the JPEG headers is a safe upper bound.
+func jpegImageSize(pixels uint) int {
+	return jpegHeaderSize + int(pixels*pixels)*3
 }
 
 // rev1ImagePageHeader returns the image page header sequence used by the
@@ -0,0 +1,848 @@
+package streamdeck
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// near returns true if both colors differ by no more than a JPEG encoding
+// would make them.
+func near(a color.Color, b color.Color) bool {
+	r1, g1, b1, _ := a.RGBA()
+	r2, g2, b2, _ := b.RGBA()
+	diff := func(x, y uint32) bool {
+		x, y = x>>8, y>>8
+		if x > y {
+			return x-y <= 8
+		}
+		return y-x <= 8
+	}
+	return diff(r1, r2) && diff(g1, g2) && diff(b1, b2)
+}
+
+// waitFor fails the test if cond doesn't become true within a second.
+func waitFor(t testing.TB, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the device")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOpenInjectedDevice(t *testing.T) {
+	f := newFakeHIDDevice()
+	d, err := NewDeviceWithHID(testDeviceInfo(PID_STREAMDECK_MK2), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Keys != 15 || d.Columns != 5 || d.Rows != 3 {
+		t.Errorf("got %d keys in %dx%d, expected 15 in 5x3", d.Keys, d.Columns, d.Rows)
+	}
+
+	if err := d.Open(); err != nil {
+		t.Fatalf("cannot open injected device: %v", err)
+	}
+	if len(f.Ops()) != 0 {
+		t.Errorf("Open sent %d commands, expected none", len(f.Ops()))
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != ErrNotOpen {
+		t.Errorf("second Close returned %v, expected ErrNotOpen", err)
+	}
+	if f.closes != 1 {
+		t.Errorf("HID device got closed %d times, expected once", f.closes)
+	}
+	if err := d.SetBrightness(50); err != ErrNotOpen {
+		t.Errorf("SetBrightness after Close returned %v, expected ErrNotOpen", err)
+	}
+}
+
+func TestUnsupportedDevice(t *testing.T) {
+	info := testDeviceInfo(0x0042)
+	if _, ok := newDevice(info); ok {
+		t.Error("unknown product ID got accepted")
+	}
+	if _, err := NewDeviceWithHID(info, newFakeHIDDevice()); err == nil {
+		t.Error("NewDeviceWithHID accepted an unknown product ID")
+	}
+}
+
+func TestFirmwareVersion(t *testing.T) {
+	d, f := newTestDevice(t)
+	f.SetFeature(append([]byte{0x05, 0x0c, 0x00, 0x00, 0x00, 0x00}, "1.00.008"...))
+
+	ver, err := d.FirmwareVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ver = strings.TrimRight(ver, "\x00"); ver != "1.00.008" {
+		t.Errorf("got firmware version %q, expected 1.00.008", ver)
+	}
+}
+
+func TestSetBrightnessReport(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	if err := d.SetBrightness(42); err != nil {
+		t.Fatal(err)
+	}
+
+	ops := f.Ops()
+	if len(ops) != 1 || !ops[0].feature {
+		t.Fatalf("got %d commands, expected a single feature report", len(ops))
+	}
+	want := make([]byte, 32)
+	copy(want, []byte{0x03, 0x08, 42})
+	if !bytes.Equal(ops[0].data, want) {
+		t.Errorf("got report % x, expected % x", ops[0].data, want)
+	}
+}
+
+func TestSetImagePages(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	if err := d.SetImage(3, d.solidImage(color.RGBA{255, 0, 0, 255})); err != nil {
+		t.Fatal(err)
+	}
+
+	var data []byte
+	ops := f.Ops()
+	for i, op := range ops {
+		if op.feature || len(op.data) != 1024 {
+			t.Fatalf("page %d: got a %d bytes command, expected a 1024 bytes write", i, len(op.data))
+		}
+		if op.data[2] != 3 {
+			t.Errorf("page %d: got key %d, expected 3", i, op.data[2])
+		}
+		if last := op.data[3] == 1; last != (i == len(ops)-1) {
+			t.Errorf("page %d of %d: got last page flag %v", i, len(ops), last)
+		}
+		if page := int(op.data[6]) | int(op.data[7])<<8; page != i {
+			t.Errorf("page %d: got page index %d", i, page)
+		}
+		length := int(op.data[4]) | int(op.data[5])<<8
+		data = append(data, op.data[8:8+length]...)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("pages don't add up to a JPEG image: %v", err)
+	}
+	if img.Bounds().Dx() != 72 || img.Bounds().Dy() != 72 {
+		t.Errorf("got a %v image, expected 72x72", img.Bounds().Size())
+	}
+	if c := img.At(36, 36); !near(c, color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("got color %v, expected red", c)
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	d, f := newTestDevice(t)
+
+	keys, err := d.ReadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	press := make([]byte, 4+15)
+	press[0] = 0x01
+	press[4+7] = 1
+	f.Report(press)
+	f.Report(make([]byte, 4+15))
+	f.Disconnect(errors.New("unplugged"))
+
+	want := []Key{{Index: 7, Pressed: true}, {Index: 7, Pressed: false}}
+	for _, w := range want {
+		if k := <-keys; k != w {
+			t.Errorf("got key event %+v, expected %+v", k, w)
+		}
+	}
+	if _, ok := <-keys; ok {
+		t.Error("key channel didn't get closed after the read error")
+	}
+}
+
+func TestKeyStateOffset(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.keyStateOffset = 6
+
+	keys, err := d.ReadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// key states start at the custom offset, the bytes before are ignored
+	report := make([]byte, 6+15)
+	report[4] = 1
+	report[6+9] = 1
+	f.Report(report)
+	f.Disconnect(errors.New("unplugged"))
+
+	if k := <-keys; k != (Key{Index: 9, Pressed: true}) {
+		t.Errorf("got key event %+v, expected key 9 pressed", k)
+	}
+	if k, ok := <-keys; ok {
+		t.Errorf("got unexpected key event %+v", k)
+	}
+}
+
+func TestWakeRestoresBlankedImages(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetSleepMode(Blank)
+	d.SetSleepFadeDuration(0)
+
+	red, green := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}
+	if err := d.SetImage(0, d.solidImage(red)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, color.Black) {
+		t.Errorf("got color %v while asleep, expected black", c)
+	}
+
+	// images set while asleep only get shown on wake
+	if err := d.SetImage(1, d.solidImage(green)); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.ImageWrites(1); n != 1 {
+		t.Errorf("got %d images on key 1 while asleep, expected only the blanking", n)
+	}
+
+	f.Reset()
+	if err := d.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.Images()); n != int(d.Keys) {
+		t.Errorf("got %d images on wake, expected %d", n, d.Keys)
+	}
+	if c := f.LastImage(t, 0).At(36, 36); !near(c, red) {
+		t.Errorf("key 0: got color %v after waking, expected red", c)
+	}
+	if c := f.LastImage(t, 1).At(36, 36); !near(c, green) {
+		t.Errorf("key 1: got color %v after waking, expected green", c)
+	}
+}
+
+// fakeClock is a Clock which returns immediately, recording how long it was
+// asked to sleep.
+type fakeClock struct {
+	mutex  sync.Mutex
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.sleeps = append(c.sleeps, d)
+}
+
+// Sleeps returns the durations the clock was asked to sleep so far.
+func (c *fakeClock) Sleeps() []time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]time.Duration(nil), c.sleeps...)
+}
+
+func TestSetFadeDelay(t *testing.T) {
+	tt := []struct {
+		delay time.Duration
+		each  time.Duration
+		steps int
+	}{
+		{0, fadeDelay, 30},
+		{time.Second / 10, time.Second / 10, 10},
+		{time.Second / 50, time.Second / 50, 50},
+		{time.Millisecond, minFadeDelay, 100},
+	}
+
+	for _, test := range tt {
+		d, _ := newTestDevice(t)
+		clock := &fakeClock{}
+		d.SetClock(clock)
+		if test.delay > 0 {
+			d.SetFadeDelay(test.delay)
+		}
+
+		if err := d.Fade(0, 100, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		// the fractional brightness steps may add up to one more step
+		sleeps := clock.Sleeps()
+		if len(sleeps) != test.steps && len(sleeps) != test.steps+1 {
+			t.Errorf("delay %v: got %d steps, expected %d", test.delay, len(sleeps), test.steps)
+		}
+		for _, s := range sleeps {
+			if s != test.each {
+				t.Errorf("delay %v: waited %v between steps, expected %v", test.delay, s, test.each)
+				break
+			}
+		}
+	}
+}
+
+func TestSleepTwice(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetClock(&fakeClock{})
+	if err := d.SetBrightness(70); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Reset()
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sleeping again sent %d commands, expected none", len(ops))
+	}
+
+	if err := d.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	if b := d.Brightness(); b != 70 {
+		t.Errorf("got brightness %d after waking, expected 70", b)
+	}
+}
+
+func TestWakeWhileAwake(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetClock(&fakeClock{})
+	if err := d.SetBrightness(70); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Reset()
+	if err := d.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("waking an awake device sent %d commands, expected none", len(ops))
+	}
+
+	// a regular sleep and wake cycle still restores the brightness
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Asleep() || d.Brightness() != 0 {
+		t.Fatalf("got brightness %d while asleep, expected 0", d.Brightness())
+	}
+	if err := d.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	if d.Asleep() || d.Brightness() != 70 {
+		t.Errorf("got brightness %d after waking, expected 70", d.Brightness())
+	}
+}
+
+// waitForSleep waits until the device went to sleep: Sleep fades out to zero
+// and then sets the brightness to zero once more.
+func waitForSleep(t *testing.T, f *fakeHIDDevice) {
+	t.Helper()
+
+	waitFor(t, asleep(f))
+}
+
+// asleep returns a condition which is true once the device went to sleep.
+func asleep(f *fakeHIDDevice) func() bool {
+	return func() bool {
+		var zeros int
+		for _, b := range f.Brightnesses() {
+			if b == 0 {
+				zeros++
+			}
+		}
+		return zeros == 2
+	}
+}
+
+func TestSubSecondSleepTimeout(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetClock(&fakeClock{})
+	d.NotifyActivity()
+
+	start := time.Now()
+	d.SetSleepTimeout(100 * time.Millisecond)
+
+	waitForSleep(t, f)
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("device went to sleep after %v, expected it after 100ms", elapsed)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	d, f := newTestDevice(t)
+	f.SetFeature(append([]byte{0x05, 0x0c, 0x00, 0x00, 0x00, 0x00}, "1.00.008"...))
+	d.SetDryRun(true)
+
+	cmds := []func() error{
+		func() error { return d.SetImage(0, d.solidImage(color.RGBA{255, 0, 0, 255})) },
+		func() error { return d.SetBrightness(30) },
+		d.Clear,
+		d.Reset,
+		func() error { return d.SendFeatureReport([]byte{0x03, 0x08, 50}) },
+	}
+	for _, cmd := range cmds {
+		if err := cmd(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sent %d commands in dry-run mode, expected none", len(ops))
+	}
+	if m := d.Stats(); m.BytesWritten == 0 || m.Commands == 0 {
+		t.Errorf("got %d bytes in %d commands, expected them to be counted", m.BytesWritten, m.Commands)
+	}
+
+	// reading from the device still works
+	if ver, err := d.FirmwareVersion(); err != nil || !strings.HasPrefix(ver, "1.00.008") {
+		t.Errorf("got firmware version %q in dry-run mode: %v", ver, err)
+	}
+
+	d.SetDryRun(false)
+	if err := d.SetBrightness(30); err != nil {
+		t.Fatal(err)
+	}
+	if ops := f.Ops(); len(ops) != 1 {
+		t.Errorf("sent %d commands after dry-run mode, expected one", len(ops))
+	}
+}
+
+func TestNonUniformKeyPixels(t *testing.T) {
+	d, f := newTestDevice(t)
+	// a model with a big center key
+	d.keyPixels = func(index uint8) uint {
+		if index == 7 {
+			return 144
+		}
+		return d.Pixels
+	}
+
+	small := image.NewRGBA(image.Rect(0, 0, 72, 72))
+	big := image.NewRGBA(image.Rect(0, 0, 144, 144))
+	if p := d.KeyPixels(7); p != 144 {
+		t.Errorf("got %d pixels for the center key, expected 144", p)
+	}
+	if p := d.KeyPixels(0); p != 72 {
+		t.Errorf("got %d pixels for a regular key, expected 72", p)
+	}
+
+	var dimErr *DimensionError
+	if err := d.SetImage(7, small); !errors.As(err, &dimErr) || dimErr.Expected != 144 {
+		t.Errorf("center key: got %v for a 72x72 image, expected a *DimensionError for 144 pixels", err)
+	}
+	if err := d.SetImage(0, big); !errors.As(err, &dimErr) || dimErr.Expected != 72 {
+		t.Errorf("regular key: got %v for a 144x144 image, expected a *DimensionError for 72 pixels", err)
+	}
+	if len(f.Ops()) > 0 {
+		t.Fatal("invalid images got sent")
+	}
+
+	if err := d.SetImage(7, big); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImage(0, small); err != nil {
+		t.Fatal(err)
+	}
+	if b := f.LastImage(t, 7).Bounds(); b.Dx() != 144 || b.Dy() != 144 {
+		t.Errorf("got a %v image on the center key, expected 144x144", b.Size())
+	}
+}
+
+// BenchmarkClear and BenchmarkFastClear compare encoding the black image for
+// every button with encoding it once.
+func BenchmarkClear(b *testing.B) {
+	d, f := newTestDevice(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := d.Clear(); err != nil {
+			b.Fatal(err)
+		}
+		f.Reset()
+	}
+}
+
+func BenchmarkFastClear(b *testing.B) {
+	d, f := newTestDevice(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := d.FastClear(); err != nil {
+			b.Fatal(err)
+		}
+		f.Reset()
+	}
+}
+
+func TestReadKeysRestart(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 5; i++ {
+		f := newFakeHIDDevice()
+		d, err := NewDeviceWithHID(testDeviceInfo(PID_STREAMDECK_MK2), f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Open(); err != nil {
+			t.Fatal(err)
+		}
+		// opening the device again must not start more command queues
+		if err := d.Open(); err != ErrAlreadyOpen {
+			t.Errorf("second Open returned %v, expected ErrAlreadyOpen", err)
+		}
+
+		keys, err := d.ReadKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.ReadKeys(); err != ErrAlreadyReading {
+			t.Errorf("second ReadKeys returned %v, expected ErrAlreadyReading", err)
+		}
+
+		// reading can be restarted once the channel got closed
+		f.Disconnect(errors.New("unplugged"))
+		for range keys {
+		}
+		keys, err = d.ReadKeys()
+		if err != nil {
+			t.Fatalf("cannot restart reading: %v", err)
+		}
+		for range keys {
+		}
+
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the read loops and command queues of all devices are gone
+	waitFor(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	})
+}
+
+func TestFadeSequence(t *testing.T) {
+	d, f := newTestDevice(t)
+	clock := &fakeClock{}
+	d.SetClock(clock)
+	d.SetFadeDelay(time.Second / 10)
+
+	tt := []struct {
+		start, end uint8
+		want       []uint8
+	}{
+		{0, 100, []uint8{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}},
+		{100, 0, []uint8{100, 90, 80, 70, 60, 50, 40, 30, 20, 10, 0}},
+		{20, 50, []uint8{20, 23, 26, 29, 32, 35, 38, 41, 44, 47, 50}},
+	}
+
+	for _, test := range tt {
+		f.Reset()
+		if err := d.Fade(test.start, test.end, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Brightnesses(); !bytes.Equal(got, test.want) {
+			t.Errorf("fade from %d to %d: got brightnesses %v, expected %v", test.start, test.end, got, test.want)
+		}
+	}
+
+	// fades shorter than a step jump to the end right away
+	f.Reset()
+	if err := d.Fade(0, 80, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Brightnesses(); !bytes.Equal(got, []uint8{80}) {
+		t.Errorf("short fade: got brightnesses %v, expected [80]", got)
+	}
+}
+
+func TestKeyOutOfRange(t *testing.T) {
+	d, f := newTestDevice(t)
+	img := d.NewKeyImage()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatal(err)
+	}
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="72" height="72"><rect width="72" height="72" fill="red"/></svg>`)
+
+	calls := map[string]func(index uint8) error{
+		"SetImage":             func(i uint8) error { return d.SetImage(i, img) },
+		"SetImageOpt":          func(i uint8) error { return d.SetImageOpt(i, img, WithFit(Contain)) },
+		"SetImageCropped":      func(i uint8) error { return d.SetImageCropped(i, img) },
+		"SetImageIfChanged":    func(i uint8) error { _, err := d.SetImageIfChanged(i, img); return err },
+		"SetImageAsync":        func(i uint8) error { return <-d.SetImageAsync(i, img) },
+		"SetImageTTL":          func(i uint8) error { return d.SetImageTTL(i, img, time.Millisecond) },
+		"SetImageOnBackground": func(i uint8) error { return d.SetImageOnBackground(i, img, color.White) },
+		"SetImageFromReader":   func(i uint8) error { return d.SetImageFromReader(i, bytes.NewReader(encoded.Bytes())) },
+		"SetImageFromSVG":      func(i uint8) error { return d.SetImageFromSVG(i, svg) },
+		"SetAnimation": func(i uint8) error {
+			return d.SetAnimation(i, []image.Image{img}, time.Millisecond, AnimationOptions{})
+		},
+		"TransitionImage":  func(i uint8) error { return d.TransitionImage(i, img, time.Millisecond, Crossfade) },
+		"Blink":            func(i uint8) error { return d.Blink(i, color.White, 1, time.Millisecond) },
+		"SetBadge":         func(i uint8) error { return d.SetBadge(i, 1) },
+		"SetKeyColorHex":   func(i uint8) error { return d.SetKeyColorHex(i, "#fff") },
+		"SetText":          func(i uint8) error { return d.SetText(i, "hi", TextOptions{}) },
+		"SetIcon":          func(i uint8) error { return d.SetIcon(i, 'x', IconOptions{}) },
+		"SetProgress":      func(i uint8) error { return d.SetProgress(i, 0.5, ProgressOptions{}) },
+		"DrawKey":          func(i uint8) error { return d.DrawKey(i, func(draw.Image) {}) },
+		"HoldToConfirm":    func(i uint8) error { return d.HoldToConfirm(i, time.Second, func() {}) },
+		"SetScrollingText": func(i uint8) error { _, err := d.SetScrollingText(i, "hi", ScrollOptions{}); return err },
+		"SetImageRange":    func(i uint8) error { return d.SetImageRange(0, i, img) },
+	}
+
+	for name, call := range calls {
+		for _, index := range []uint8{d.Keys, 255} {
+			if err := call(index); !errors.Is(err, ErrKeyOutOfRange) {
+				t.Errorf("%s(%d): got %v, expected ErrKeyOutOfRange", name, index, err)
+			}
+		}
+	}
+	if ops := f.Ops(); len(ops) > 0 {
+		t.Errorf("sent %d commands, expected none", len(ops))
+	}
+	if d.IsValidKey(d.Keys) || !d.IsValidKey(d.Keys-1) {
+		t.Errorf("IsValidKey doesn't match the device's %d keys", d.Keys)
+	}
+}
+
+// testModels lists the product IDs of all supported models.
+var testModels = []uint16{
+	PID_STREAMDECK,
+	PID_STREAMDECK_V2,
+	PID_STREAMDECK_MK2,
+	PID_STREAMDECK_MINI,
+	PID_STREAMDECK_MINI_MK2,
+	PID_STREAMDECK_XL,
+}
+
+// checkKeys fails the test if any key event is for a key the device doesn't
+// have.
+func checkKeys(t *testing.T, d *Device, keys []Key) {
+	t.Helper()
+	for _, k := range keys {
+		if !d.IsValidKey(k.Index) {
+			t.Fatalf("got an event for key %d of a device with %d keys", k.Index, d.Keys)
+		}
+	}
+}
+
+func TestParseKeyStatesAllBytes(t *testing.T) {
+	for _, pid := range testModels {
+		d, ok := newDevice(testDeviceInfo(pid))
+		if !ok {
+			t.Fatalf("model %#04x isn't supported", pid)
+		}
+
+		// every byte value at every position, including past the key states
+		size := d.keyStateOffset + len(d.keyState) + 8
+		for pos := 0; pos < size; pos++ {
+			for v := 0; v < 256; v++ {
+				report := make([]byte, size)
+				report[pos] = byte(v)
+				checkKeys(t, &d, d.parseKeyReport(&d, report))
+			}
+		}
+
+		// overlong reports must not wrap around to the first keys
+		for i := range d.keyState {
+			d.keyState[i] = 0
+		}
+		report := make([]byte, d.keyStateOffset+256+1)
+		report[d.keyStateOffset+256] = 1
+		if keys := d.parseKeyReport(&d, report); len(keys) > 0 {
+			t.Errorf("model %#04x: got %v for a key past the report, expected none", pid, keys)
+		}
+
+		// the index translation stays within the device's keys
+		for index := 0; index < 256; index++ {
+			translated := d.translateKeyIndex(uint8(index), d.Columns)
+			if index < int(d.Keys) && !d.IsValidKey(translated) {
+				t.Errorf("model %#04x: key %d got translated to %d", pid, index, translated)
+			}
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	for _, asleep := range []bool{false, true} {
+		d, f := newTestDevice(t)
+		d.SetClock(&fakeClock{})
+		d.SetSleepMode(Blank)
+		if asleep {
+			if err := d.Sleep(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		f.Reset()
+		red := color.RGBA{255, 0, 0, 255}
+		if err := d.Resume(map[uint8]image.Image{0: d.solidImage(red)}, 80); err != nil {
+			t.Fatal(err)
+		}
+
+		// all images are written while the brightness is zero
+		var dark, lit bool
+		for i, op := range f.Ops() {
+			if op.feature && bytes.HasPrefix(op.data, c_REV2_BRIGHTNESS) {
+				b := op.data[len(c_REV2_BRIGHTNESS)]
+				dark = dark || b == 0
+				lit = lit || b > 0
+				continue
+			}
+			if !dark && !asleep {
+				t.Fatalf("asleep %v: command %d wrote an image before the brightness got set to zero", asleep, i)
+			}
+			if lit {
+				t.Fatalf("asleep %v: command %d wrote an image after the brightness rose", asleep, i)
+			}
+		}
+		if b := f.Brightnesses(); b[len(b)-1] != 80 {
+			t.Errorf("asleep %v: got final brightness %d, expected 80", asleep, b[len(b)-1])
+		}
+		if c := f.LastImage(t, 0).At(36, 36); !near(c, red) {
+			t.Errorf("asleep %v: got color %v, expected red", asleep, c)
+		}
+		if d.Asleep() {
+			t.Errorf("asleep %v: device is still asleep after Resume", asleep)
+		}
+	}
+}
+
+func TestSetImageCommandSequence(t *testing.T) {
+	for _, pid := range testModels {
+		f := newFakeHIDDevice()
+		d, err := NewDeviceWithHID(testDeviceInfo(pid), f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.SetImage(1, d.solidImage(color.RGBA{255, 0, 0, 255})); err != nil {
+			t.Fatal(err)
+		}
+
+		// the last page flag commits the image, no other command follows
+		ops := f.Ops()
+		if len(ops) == 0 {
+			t.Fatalf("model %#04x: got no commands", pid)
+		}
+		for i, op := range ops {
+			if op.feature || op.data[0] != 0x02 {
+				t.Fatalf("model %#04x: command %d isn't an image page", pid, i)
+			}
+			last := op.data[3] == 1
+			if op.data[1] == 0x01 {
+				last = op.data[4] == 1
+			}
+			if last != (i == len(ops)-1) {
+				t.Errorf("model %#04x: page %d of %d has last page flag %v", pid, i, len(ops), last)
+			}
+		}
+		_ = d.Close()
+	}
+}
+
+// BenchmarkToJPEG and BenchmarkToJPEGUnpooled compare the allocations of
+// encoding with and without the pooled buffers.
+func BenchmarkToJPEG(b *testing.B) {
+	img := halvesImage(72, 72)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := toJPEG(img, 100); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkToJPEGUnpooled(b *testing.B) {
+	img := halvesImage(72, 72)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buffer bytes.Buffer
+		if err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 100}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPauseSleep(t *testing.T) {
+	d, f := newTestDevice(t)
+	d.SetClock(&fakeClock{})
+	d.SetSleepTimeout(50 * time.Millisecond)
+	d.PauseSleep()
+
+	time.Sleep(200 * time.Millisecond)
+	if asleep(f)() {
+		t.Fatal("device went to sleep while sleep was paused")
+	}
+
+	// the timeout starts counting when resuming
+	start := time.Now()
+	d.ResumeSleep()
+	waitForSleep(t, f)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("device went to sleep %v after resuming, expected the whole timeout", elapsed)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	d, f := newTestDevice(t)
+	clock := &fakeClock{}
+	d.SetClock(clock)
+	d.SetBrightnessVerify(true)
+	d.SetRetryDelay(20 * time.Millisecond)
+
+	var attempts []uint
+	d.SetRetryObserver(func(cmd string, attempt uint, err error) {
+		attempts = append(attempts, attempt)
+	})
+
+	unplugged := errors.New("unplugged")
+	f.FailFeatures(unplugged, unplugged)
+	if err := d.SetBrightness(50); err != nil {
+		t.Fatal(err)
+	}
+
+	if b := f.Brightnesses(); len(b) != 1 || b[0] != 50 {
+		t.Errorf("got brightnesses %v, expected 50 once", b)
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("got retry attempts %v, expected [1 2]", attempts)
+	}
+
+	// every retry waits twice as long as the one before, plus up to 50% jitter
+	sleeps := clock.Sleeps()
+	if len(sleeps) != 2 {
+		t.Fatalf("got sleeps %v, expected two", sleeps)
+	}
+	for i, base := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond} {
+		if sleeps[i] < base || sleeps[i] > base+base/2 {
+			t.Errorf("retry %d waited %v, expected between %v and %v", i+1, sleeps[i], base, base+base/2)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package streamdeck
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/draw"
+)
+
+// SetImageFromSVG rasterizes an SVG image at the key resolution and sets it on
+// a button. The SVG's view box gets scaled to fill the whole key. Transparent
+// areas are shown black, as the device can't display transparency.
+func (d *Device) SetImageFromSVG(index uint8, svg []byte) error {
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+
+	img, err := rasterizeSVG(svg, int(d.KeyPixels(index)))
+	if err != nil {
+		return err
+	}
+	return d.SetImage(index, img)
+}
+
+// rasterizeSVG renders an SVG image onto a black square of the given size.
+func rasterizeSVG(svg []byte, size int) (*image.RGBA, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(svg))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse SVG: %v", err)
+	}
+	icon.SetTarget(0, 0, float64(size), float64(size))
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+	return img, nil
+}
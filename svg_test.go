@@ -0,0 +1,40 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// halvesSVG has a red left half and a transparent right half.
+const halvesSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">
+	<rect x="0" y="0" width="5" height="10" fill="#ff0000"/>
+</svg>`
+
+func TestRasterizeSVG(t *testing.T) {
+	for _, size := range []int{72, 96} {
+		img, err := rasterizeSVG([]byte(halvesSVG), size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b != image.Rect(0, 0, size, size) {
+			t.Errorf("got bounds %v, expected %dx%d", b, size, size)
+		}
+
+		// the view box gets scaled to the whole image, transparency is black
+		for p, want := range map[image.Point]color.Color{
+			{size / 4, size / 2}:     color.RGBA{255, 0, 0, 255},
+			{size * 3 / 4, size / 2}: color.RGBA{0, 0, 0, 255},
+		} {
+			if c := img.At(p.X, p.Y); !near(c, want) {
+				t.Errorf("size %d: got color %v at %v, expected %v", size, c, p, want)
+			}
+		}
+	}
+}
+
+func TestRasterizeSVGInvalid(t *testing.T) {
+	if _, err := rasterizeSVG([]byte("<svg"), 72); err == nil {
+		t.Error("expected broken SVG to fail")
+	}
+}
@@ -0,0 +1,192 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextOptions customizes how SetText renders text. Unset colors default to
+// white text on a black background. Without a Font, text gets rendered with a
+// small built-in bitmap font, ignoring Size.
+type TextOptions struct {
+	Foreground color.Color
+	Background color.Color
+
+	// Font is an OpenType font to render the text with, e.g. a brand or icon
+	// font parsed with opentype.Parse.
+	Font *opentype.Font
+	// Size is the font size in pixels. It defaults to a fifth of the key
+	// resolution.
+	Size float64
+}
+
+// faceKey identifies a font face by its font and size.
+type faceKey struct {
+	font *opentype.Font
+	size float64
+}
+
+// cachedFace is a font face along with a lock, as faces must not be used
+// concurrently.
+type cachedFace struct {
+	sync.Mutex
+	face font.Face
+}
+
+// faces caches the font faces created for rendering text, so fonts don't get
+// set up again for every text.
+var faces = struct {
+	sync.Mutex
+	m map[faceKey]*cachedFace
+}{m: make(map[faceKey]*cachedFace)}
+
+// textFace returns the cached face for the font and size, creating it if
+// needed. Without a font, it returns the built-in bitmap font. The caller must
+// lock the face while using it.
+func textFace(f *opentype.Font, size float64) (*cachedFace, error) {
+	if f == nil {
+		return &cachedFace{face: basicfont.Face7x13}, nil
+	}
+	key := faceKey{font: f, size: size}
+
+	faces.Lock()
+	defer faces.Unlock()
+
+	if cached, ok := faces.m[key]; ok {
+		return cached, nil
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create font face: %v", err)
+	}
+
+	cached := &cachedFace{face: face}
+	faces.m[key] = cached
+	return cached, nil
+}
+
+// KeyPos is the position of a button on the device, starting with 0, 0 for
+// the top-left button.
+type KeyPos struct {
+	Row uint8
+	Col uint8
+}
+
+// SetText renders text centered on a button. Newlines split the text into
+// multiple lines. It returns an error if the text doesn't fit on the button.
+func (d *Device) SetText(index uint8, text string, opts TextOptions) error {
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+	img, err := renderText(int(d.KeyPixels(index)), text, opts)
+	if err != nil {
+		return err
+	}
+	return d.SetImage(index, img)
+}
+
+// SetAllText renders text on multiple buttons at once, keyed by button index.
+// All texts get rendered before any button is updated, so no button changes
+// if one of the texts doesn't fit. Buttons without a text are left untouched.
+func (d *Device) SetAllText(texts map[uint8]string, opts TextOptions) error {
+	images := make(map[uint8]image.Image, len(texts))
+	for index, text := range texts {
+		if err := d.validateKey(index); err != nil {
+			return err
+		}
+
+		img, err := renderText(int(d.KeyPixels(index)), text, opts)
+		if err != nil {
+			return fmt.Errorf("key %d: %v", index, err)
+		}
+		images[index] = img
+	}
+
+	return d.SetImagesFunc(func(index uint8) (image.Image, error) {
+		return images[index], nil
+	})
+}
+
+// SetAllTextGrid works like SetAllText, but takes the texts keyed by the row
+// and column of their buttons.
+func (d *Device) SetAllTextGrid(texts map[KeyPos]string, opts TextOptions) error {
+	indexed := make(map[uint8]string, len(texts))
+	for pos, text := range texts {
+		if pos.Row >= d.Rows || pos.Col >= d.Columns {
+			return fmt.Errorf("%w: key %d, %d, device has %dx%d keys", ErrKeyOutOfRange, pos.Row, pos.Col, d.Rows, d.Columns)
+		}
+		indexed[pos.Row*d.Columns+pos.Col] = text
+	}
+	return d.SetAllText(indexed, opts)
+}
+
+// renderText returns a square image of the given size with the text centered
+// on it.
+func renderText(size int, text string, opts TextOptions) (*image.RGBA, error) {
+	fg := opts.Foreground
+	if fg == nil {
+		fg = color.White
+	}
+	bg := opts.Background
+	if bg == nil {
+		bg = color.Black
+	}
+
+	fontSize := opts.Size
+	if fontSize <= 0 {
+		fontSize = float64(size) / 5
+	}
+	f, err := textFace(opts.Font, fontSize)
+	if err != nil {
+		return nil, err
+	}
+	f.Lock()
+	defer f.Unlock()
+
+	face := f.face
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	lines := strings.Split(text, "\n")
+	if len(lines)*lineHeight > size {
+		return nil, fmt.Errorf("text %q doesn't fit on the key: too many lines", text)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(fg),
+		Face: face,
+	}
+
+	top := (size - len(lines)*lineHeight) / 2
+	for i, line := range lines {
+		width := drawer.MeasureString(line)
+		if width.Ceil() > size {
+			return nil, fmt.Errorf("text %q doesn't fit on the key: line %q is too wide", text, line)
+		}
+
+		drawer.Dot = fixed.Point26_6{
+			X: (fixed.I(size) - width) / 2,
+			Y: fixed.I(top+i*lineHeight) + metrics.Ascent,
+		}
+		drawer.DrawString(line)
+	}
+
+	return img, nil
+}
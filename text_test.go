@@ -0,0 +1,74 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// litPixels counts the pixels of an image which aren't black.
+func litPixels(img image.Image) int {
+	var n int
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); r|g|b != 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestRenderTextCustomFont(t *testing.T) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bitmap, err := renderText(72, "Hi", TextOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := renderText(72, "Hi", TextOptions{Font: f, Size: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the text is much larger than with the 7x13 bitmap font
+	if n, m := litPixels(img), litPixels(bitmap); n == 0 || n <= 2*m {
+		t.Errorf("got %d lit pixels with the custom font and %d with the bitmap font", n, m)
+	}
+
+	red := color.RGBA{255, 0, 0, 255}
+	img, err = renderText(72, "Hi", TextOptions{Font: f, Size: 30, Background: red})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := img.At(2, 2); c != color.Color(red) {
+		t.Errorf("got background color %v, expected red", c)
+	}
+}
+
+func TestTextFaceCache(t *testing.T) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := textFace(f, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := textFace(f, 20)
+	c, _ := textFace(f, 24)
+	if a != b {
+		t.Error("face of the same size got created again")
+	}
+	if a == c {
+		t.Error("faces of different sizes are the same")
+	}
+}
@@ -0,0 +1,63 @@
+package streamdeck
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Throttled brightness changes get applied at most 30 times a second.
+	brightnessThrottleInterval = time.Second / 30
+)
+
+// brightnessThrottle coalesces rapid brightness changes.
+type brightnessThrottle struct {
+	sync.Mutex
+	interval  time.Duration
+	pending   uint8
+	scheduled bool
+	last      time.Time
+}
+
+// SetBrightnessThrottled sets the brightness like SetBrightness, but applies
+// at most one change per throttle interval, dropping intermediate values. The
+// last value always gets applied. This is meant for interactive adjustments,
+// e.g. while dragging a slider.
+func (d *Device) SetBrightnessThrottled(percent uint8) {
+	t := d.throttle
+	t.Lock()
+	defer t.Unlock()
+
+	t.pending = percent
+	if t.scheduled {
+		return
+	}
+
+	t.scheduled = true
+	wait := t.interval - time.Since(t.last)
+	if wait < 0 {
+		wait = 0
+	}
+	time.AfterFunc(wait, func() {
+		t.Lock()
+		percent := t.pending
+		t.scheduled = false
+		t.last = time.Now()
+		t.Unlock()
+
+		_ = d.SetBrightness(percent)
+	})
+}
+
+// SetBrightnessThrottleRate sets how many throttled brightness changes get
+// applied per second at most. It defaults to 30.
+func (d *Device) SetBrightnessThrottleRate(perSecond int) {
+	if perSecond < 1 {
+		perSecond = 1
+	}
+
+	d.throttle.Lock()
+	defer d.throttle.Unlock()
+
+	d.throttle.interval = time.Second / time.Duration(perSecond)
+}
@@ -0,0 +1,64 @@
+package streamdeck
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// commandTimeouts holds how long to wait for each type of command.
+type commandTimeouts struct {
+	sync.Mutex
+	m map[string]time.Duration
+}
+
+// SetCommandTimeout sets how long to wait for a single command of the given
+// type before giving up with an error wrapping ErrTimeout. The types are the
+// ones Metrics.Errors uses: "firmware", "serial", "reset", "brightness",
+// "image", "standby", "profile" and "raw". For images, the timeout applies to
+// every page of an image on its own. Zero, the default, waits forever.
+//
+// The device may still be busy with a command that timed out, in which case
+// later commands wait for it to finish first.
+func (d *Device) SetCommandTimeout(cmd string, t time.Duration) {
+	d.timeouts.Lock()
+	defer d.timeouts.Unlock()
+
+	if t <= 0 {
+		delete(d.timeouts.m, cmd)
+		return
+	}
+	d.timeouts.m[cmd] = t
+}
+
+// do runs fn on the I/O goroutine, waiting no longer than the timeout set for
+// the command type.
+func (d Device) do(cmd string, fn func() (int, error)) (int, error) {
+	if !d.opened {
+		return 0, ErrNotOpen
+	}
+
+	d.timeouts.Lock()
+	timeout := d.timeouts.m[cmd]
+	d.timeouts.Unlock()
+
+	type result struct {
+		n   int
+		err error
+	}
+	res := make(chan result, 1)
+	err := d.io.DoTimeout(func() error {
+		n, err := fn()
+		res <- result{n, err}
+		return nil
+	}, timeout)
+	if err == ErrTimeout {
+		return 0, fmt.Errorf("%s command after %v: %w", cmd, timeout, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	r := <-res
+	return r.n, r.err
+}
@@ -0,0 +1,85 @@
+package streamdeck
+
+import "image"
+
+// TouchType describes the kind of a touch strip event.
+type TouchType int
+
+// Touch strip event types.
+const (
+	Tap TouchType = iota
+	LongPress
+	Swipe
+)
+
+// Touch holds a touch strip event. For swipes, X and Y are where the swipe
+// started, EndX and EndY where it ended.
+type Touch struct {
+	Type TouchType
+	X    int
+	Y    int
+	EndX int
+	EndY int
+}
+
+// SetTouchStripImage sets the image shown on the device's touch strip. It
+// returns ErrUnsupported for devices without a touch strip.
+func (d *Device) SetTouchStripImage(img image.Image) error {
+	if !d.Capabilities().Has(CapTouchStrip) {
+		return ErrUnsupported
+	}
+
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	return d.touchStripImage(d, img)
+}
+
+// ReadTouch returns a channel, which it will use to emit touch strip events.
+// Touch events are read by the same loop as key events, so ReadKeys needs to
+// be called as well. It returns ErrUnsupported for devices without a touch
+// strip. Once requested, the channel must be drained continuously, otherwise
+// it blocks the delivery of key events as well.
+func (d *Device) ReadTouch() (chan Touch, error) {
+	if !d.Capabilities().Has(CapTouchStrip) {
+		return nil, ErrUnsupported
+	}
+
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	if d.touchEvents == nil {
+		d.touchEvents = make(chan Touch)
+	}
+	return d.touchEvents, nil
+}
+
+// emitTouches parses the touch strip events of a report and sends them to the
+// channel returned by ReadTouch.
+func (d *Device) emitTouches(report []byte) {
+	if d.parseTouchReport == nil {
+		return
+	}
+
+	d.inputMutex.Lock()
+	tch := d.touchEvents
+	d.inputMutex.Unlock()
+	if tch == nil {
+		return
+	}
+
+	for _, t := range d.parseTouchReport(d, report) {
+		tch <- t
+	}
+}
+
+// closeTouches closes the channel returned by ReadTouch.
+func (d *Device) closeTouches() {
+	d.inputMutex.Lock()
+	defer d.inputMutex.Unlock()
+
+	if d.touchEvents != nil {
+		close(d.touchEvents)
+		d.touchEvents = nil
+	}
+}
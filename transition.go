@@ -0,0 +1,98 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// Transitions get drawn with 25 frames a second.
+	transitionFrameDelay = time.Second / 25
+)
+
+// Transition determines how TransitionImage blends from one image to the
+// next.
+type Transition int
+
+// Transition types.
+const (
+	// Crossfade fades the new image in over the previous one.
+	Crossfade Transition = iota
+	// SlideLeft slides the new image in from the right, pushing the previous
+	// image out to the left.
+	SlideLeft
+)
+
+// TransitionImage changes the image of a button to the given one, animating
+// the change over the given duration. If the button has no cached image to
+// transition from, the image gets set right away. Setting another image on the
+// button stops the transition.
+func (d *Device) TransitionImage(index uint8, to image.Image, duration time.Duration, transition Transition) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if err := d.validateKeyImage(index, to); err != nil {
+		return err
+	}
+
+	from, ok := d.images.Get(index)
+	if !ok || duration <= 0 {
+		return d.SetImage(index, to)
+	}
+
+	done := d.startAnimation(index)
+	defer d.finishAnimation(index, done)
+
+	steps := int(duration / transitionFrameDelay)
+	if steps < 1 {
+		steps = 1
+	}
+
+	for i := 1; i <= steps; i++ {
+		frame := newKeyImage(to)
+		if i < steps {
+			frame.img = blendImages(from.img, to, float64(i)/float64(steps), transition)
+		}
+		if ok, err := d.setAnimationFrame(index, frame, done, i == steps); !ok || err != nil {
+			return err
+		}
+		if i == steps {
+			break
+		}
+
+		select {
+		case <-time.After(transitionFrameDelay):
+		case <-done:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// blendImages returns a frame of the transition from one image to another of
+// the same size, progress running from 0 to 1.
+func blendImages(from, to image.Image, progress float64, transition Transition) *image.RGBA {
+	b := to.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	r := dst.Bounds()
+
+	switch transition {
+	case SlideLeft:
+		offset := int(math.Round(float64(r.Dx()) * progress))
+		draw.Draw(dst, r, from, from.Bounds().Min.Add(image.Pt(offset, 0)), draw.Src)
+		draw.Draw(dst, image.Rect(r.Max.X-offset, 0, r.Max.X, r.Max.Y), to, b.Min, draw.Src)
+
+	default:
+		alpha := uint8(math.Round(progress * 255))
+		draw.Draw(dst, r, from, from.Bounds().Min, draw.Src)
+		draw.DrawMask(dst, r, to, b.Min, image.NewUniform(color.Alpha{alpha}), image.Point{}, draw.Over)
+	}
+
+	return dst
+}
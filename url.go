@@ -0,0 +1,89 @@
+package streamdeck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxImageDownloadSize limits how many bytes SetImageFromURL reads.
+const maxImageDownloadSize = 10 << 20
+
+// httpClient holds the client SetImageFromURL uses to fetch images.
+type httpClient struct {
+	sync.Mutex
+	c *http.Client
+}
+
+// Get returns the client, or http.DefaultClient if none was set.
+func (h *httpClient) Get() *http.Client {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.c == nil {
+		return http.DefaultClient
+	}
+	return h.c
+}
+
+// SetHTTPClient sets the client SetImageFromURL uses to fetch images. Passing
+// nil restores http.DefaultClient. It is safe to call while images are being
+// fetched.
+func (d *Device) SetHTTPClient(c *http.Client) {
+	d.httpClient.Lock()
+	defer d.httpClient.Unlock()
+
+	d.httpClient.c = c
+}
+
+// SetImageFromURL fetches a GIF, JPEG, PNG or WebP image and sets it on a button,
+// scaling it to the device's key resolution. The context cancels both the
+// request and sending the image.
+func (d *Device) SetImageFromURL(ctx context.Context, index uint8, url string) error {
+	if !d.opened {
+		return ErrNotOpen
+	}
+
+	if err := d.validateKey(index); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.httpClient.Get().Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot fetch image from %s: %s", url, resp.Status)
+	}
+
+	if resp.ContentLength > maxImageDownloadSize {
+		return fmt.Errorf("cannot fetch image from %s: %w (%d bytes)", url, ErrImageTooLarge, resp.ContentLength)
+	}
+	// read one byte more than allowed to tell a truncated image from one
+	// which just fits
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageDownloadSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot fetch image from %s: %w", url, err)
+	}
+	if len(data) > maxImageDownloadSize {
+		return fmt.Errorf("cannot fetch image from %s: %w", url, ErrImageTooLarge)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot decode image from %s (%s): %w", url, resp.Header.Get("Content-Type"), err)
+	}
+
+	return d.SetImageOpt(index, img, WithFit(Stretch), WithContext(ctx))
+}
@@ -0,0 +1,105 @@
+package streamdeck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+func TestSetImageFromURL(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	var red bytes.Buffer
+	if err := png.Encode(&red, img); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/red.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(red.Bytes())
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("not an image"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, maxImageDownloadSize+1))
+	})
+	mux.HandleFunc("/large-chunked", func(w http.ResponseWriter, r *http.Request) {
+		// flushing first keeps the server from sending a Content-Length
+		w.(http.Flusher).Flush()
+		_, _ = w.Write(make([]byte, maxImageDownloadSize+1))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Run("png", func(t *testing.T) {
+		d, f := newTestDevice(t)
+		if err := d.SetImageFromURL(context.Background(), 0, srv.URL+"/red.png"); err != nil {
+			t.Fatal(err)
+		}
+		if c := f.LastImage(t, 0).At(36, 36); !near(c, color.RGBA{255, 0, 0, 255}) {
+			t.Errorf("got color %v, expected red", c)
+		}
+	})
+
+	for _, test := range []struct {
+		name string
+		path string
+		err  error
+	}{
+		{"not found", "/missing.png", nil},
+		{"not an image", "/text", nil},
+		{"too large", "/large", ErrImageTooLarge},
+		{"too large without length", "/large-chunked", ErrImageTooLarge},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d, f := newTestDevice(t)
+			err := d.SetImageFromURL(context.Background(), 0, srv.URL+test.path)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Errorf("got error %v, expected %v", err, test.err)
+			}
+			if n := len(f.Images()); n != 0 {
+				t.Errorf("got %d images, expected none", n)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		d, f := newTestDevice(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := d.SetImageFromURL(ctx, 0, srv.URL+"/red.png"); !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, expected context.Canceled", err)
+		}
+		if n := len(f.Images()); n != 0 {
+			t.Errorf("got %d images, expected none", n)
+		}
+	})
+}
+
+func TestSetHTTPClient(t *testing.T) {
+	d, _ := newTestDevice(t)
+
+	client := &http.Client{}
+	d.SetHTTPClient(client)
+	if c := d.httpClient.Get(); c != client {
+		t.Errorf("got client %p, expected %p", c, client)
+	}
+	d.SetHTTPClient(nil)
+	if c := d.httpClient.Get(); c != http.DefaultClient {
+		t.Errorf("got client %p, expected http.DefaultClient", c)
+	}
+}